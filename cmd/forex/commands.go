@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/database"
+	"github.com/rs/zerolog"
+)
+
+// openDatabase connects to the configured database for a one-off command,
+// first bringing the schema up to date when migrate is set.
+func openDatabase(logger *zerolog.Logger, cfg *config.Config, migrate bool) (*database.Database, error) {
+	if migrate {
+		migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancelMigrate()
+		if err := database.Migrate(migrateCtx, logger, cfg); err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	db, err := database.New(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return db, nil
+}
@@ -13,12 +13,27 @@ import (
 	"github.com/justinndidit/forex/internal/database"
 	"github.com/justinndidit/forex/internal/logger"
 	"github.com/justinndidit/forex/internal/routes"
+	"github.com/justinndidit/forex/internal/scheduler"
 	"github.com/justinndidit/forex/internal/server"
 )
 
 const DefaultContextTimeout = 30
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "restore":
+			runRestore(os.Args[2:])
+			return
+		}
+	}
+
 	logger := logger.NewLogger()
 	logger.Info().Msg("Application starting...")
 
@@ -46,7 +61,10 @@ func main() {
 	}
 	logger.Info().Msg("Database connected successfully")
 
-	app := app.NewApp(cfg, &logger, db)
+	app, err := app.NewApp(cfg, &logger, db)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialize application")
+	}
 	r := routes.SetupAuthRoutes(app)
 
 	srv, err := server.New(app, cfg)
@@ -59,6 +77,11 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	// Start refresh job worker, recovering jobs left by a previous run
+	if err = app.Jobs.Start(ctx); err != nil {
+		logger.Fatal().Err(err).Msg("failed to start refresh job runner")
+	}
+
 	// Start server
 	go func() {
 		logger.Info().Msg("Starting HTTP server...")
@@ -69,6 +92,17 @@ func main() {
 
 	logger.Info().Msg("Server is ready to accept connections")
 
+	// Start background refresh scheduler
+	var sched *scheduler.Scheduler
+	if cfg.Refresh.ScheduleEnabled {
+		sched, err = scheduler.New(cfg.Refresh, &logger, app.Jobs)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to initialize refresh scheduler")
+		}
+		sched.Start(ctx)
+		logger.Info().Msg("Refresh scheduler started")
+	}
+
 	// Wait for interrupt signal
 	<-ctx.Done()
 
@@ -77,6 +111,18 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), DefaultContextTimeout*time.Second)
 	defer cancel()
 
+	// Stop the scheduler and job worker first so an in-flight refresh
+	// finishes before the database pool is closed. Refresh requests that
+	// arrive meanwhile are refused with 503 rather than queued.
+	if sched != nil {
+		if err = sched.Stop(shutdownCtx); err != nil {
+			logger.Error().Err(err).Msg("refresh scheduler did not stop in time")
+		}
+	}
+	if err = app.Jobs.Stop(shutdownCtx); err != nil {
+		logger.Error().Err(err).Msg("refresh job runner did not stop in time")
+	}
+
 	if err = srv.Shutdown(shutdownCtx); err != nil {
 		logger.Fatal().Err(err).Msg("server forced to shutdown")
 	}
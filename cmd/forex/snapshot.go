@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/logger"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/repository"
+	"github.com/justinndidit/forex/internal/service"
+	"github.com/rs/zerolog"
+)
+
+const exportUsage = `Usage: forex export [--output FILE]
+
+Writes a snapshot of the stored dataset as NDJSON, to stdout unless an
+output file is given.
+
+`
+
+const restoreUsage = `Usage: forex restore --input FILE
+
+Replaces the stored dataset with a snapshot written by forex export. The
+snapshot must come from a database at the current schema version.
+
+`
+
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), exportUsage)
+		flags.PrintDefaults()
+	}
+	output := flags.String("output", "", "file to write the snapshot to")
+	flags.Parse(args)
+
+	// The snapshot may go to stdout, so logs go to stderr.
+	logger := logger.NewLoggerTo(os.Stderr)
+
+	if err := export(&logger, *output); err != nil {
+		logger.Fatal().Err(err).Msg("export failed")
+	}
+	logger.Info().Msg("export completed")
+}
+
+// export writes the snapshot to output, or stdout when it is empty. Errors
+// are returned rather than fatal so the database and file are closed first.
+func export(logger *zerolog.Logger, output string) (err error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	db, err := openDatabase(logger, cfg, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create snapshot file: %w", err)
+		}
+		defer func() {
+			if closeErr := file.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("failed to write snapshot file: %w", closeErr)
+			}
+		}()
+		w = file
+	}
+
+	snapshots := service.NewSnapshotService(logger, repository.NewForexRepository(logger, db))
+	return snapshots.Export(context.Background(), w)
+}
+
+func runRestore(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), restoreUsage)
+		flags.PrintDefaults()
+	}
+	input := flags.String("input", "", "snapshot file to restore")
+	flags.Parse(args)
+
+	if *input == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	logger := logger.NewLogger()
+
+	result, err := restore(&logger, *input)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("restore failed")
+	}
+
+	logger.Info().
+		Uint("schema_version", result.SchemaVersion).
+		Int64("rows", result.Rows).
+		Interface("tables", result.Tables).
+		Msg("restore completed")
+}
+
+// restore loads the snapshot in input. Like export, it returns its errors
+// so the deferred closes run.
+func restore(logger *zerolog.Logger, input string) (*model.SnapshotRestoreResponse, error) {
+	file, err := os.Open(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot file: %w", err)
+	}
+	defer file.Close()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	db, err := openDatabase(logger, cfg, true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	snapshots := service.NewSnapshotService(logger, repository.NewForexRepository(logger, db))
+	return snapshots.Restore(context.Background(), file)
+}
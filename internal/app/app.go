@@ -3,32 +3,64 @@ package app
 import (
 	"github.com/justinndidit/forex/internal/config"
 	"github.com/justinndidit/forex/internal/database"
+	"github.com/justinndidit/forex/internal/estimator"
 	"github.com/justinndidit/forex/internal/handler"
+	"github.com/justinndidit/forex/internal/provider"
 	"github.com/justinndidit/forex/internal/repository"
+	"github.com/justinndidit/forex/internal/service"
 	"github.com/justinndidit/forex/internal/util"
 	"github.com/rs/zerolog"
 )
 
 type Application struct {
-	Config  *config.Config
-	Logger  *zerolog.Logger
-	DB      *database.Database
-	Handler *handler.ForexHandler
-	repo    *repository.ForexRepository
-	ImgGen  *util.ImageService
+	Config    *config.Config
+	Logger    *zerolog.Logger
+	DB        *database.Database
+	Handler   *handler.ForexHandler
+	Refresher *service.RefreshService
+	Jobs      *service.JobRunner
+	Snapshots *service.SnapshotService
+	repo      *repository.ForexRepository
+	ImgGen    *util.ImageService
 }
 
-func NewApp(cfg *config.Config, logger *zerolog.Logger, db *database.Database) *Application {
+func NewApp(cfg *config.Config, logger *zerolog.Logger, db *database.Database) (*Application, error) {
+	baseCurrency := cfg.Refresh.BaseCurrency
+	if baseCurrency == "" {
+		baseCurrency = service.DefaultBaseCurrency
+	}
+
+	countrySource, err := provider.NewCountrySource(cfg.Refresh.CountrySource, cfg.Sources, cfg.Refresh.UpstreamCacheDir, logger)
+	if err != nil {
+		return nil, err
+	}
+	rateSource, err := provider.NewRateSources(cfg.Refresh.RateSources, cfg.Refresh.RateMode, baseCurrency, cfg.Sources, cfg.Refresh.UpstreamCacheDir, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	gdpEstimator, err := estimator.New(cfg.Refresh)
+	if err != nil {
+		return nil, err
+	}
+
 	repo := repository.NewForexRepository(logger, db)
 	imgGen := util.NewImageService(logger)
+	refresher := service.NewRefreshService(logger, repo, imgGen, cfg.Refresh, countrySource, rateSource, gdpEstimator)
+	jobs := service.NewJobRunner(logger, repo, refresher, cfg.Refresh.Timeout, cfg.Refresh.QueueSize)
 
-	handler := handler.NewForexHandler(logger, db, repo, imgGen)
+	snapshots := service.NewSnapshotService(logger, repo)
+
+	handler := handler.NewForexHandler(logger, db, repo, jobs, snapshots)
 	return &Application{
-		Config:  cfg,
-		Logger:  logger,
-		DB:      db,
-		repo:    repo,
-		Handler: handler,
-		ImgGen:  imgGen,
-	}
+		Config:    cfg,
+		Logger:    logger,
+		DB:        db,
+		repo:      repo,
+		Handler:   handler,
+		Refresher: refresher,
+		Jobs:      jobs,
+		Snapshots: snapshots,
+		ImgGen:    imgGen,
+	}, nil
 }
@@ -14,6 +14,9 @@ import (
 type Config struct {
 	Database DatabaseConfig `koanf:"database" validate:"required"`
 	Server   ServerConfig   `koanf:"server" validate:"required"`
+	Refresh  RefreshConfig  `koanf:"refresh"`
+	// Sources holds per-provider settings keyed by provider name.
+	Sources map[string]SourceConfig `koanf:"source" validate:"dive"`
 }
 
 type DatabaseConfig struct {
@@ -37,6 +40,63 @@ type ServerConfig struct {
 	CORSAllowedOrigins []string `koanf:"cors_allowed_origins" validate:"required"`
 }
 
+// RefreshConfig controls refresh jobs and the background scheduler. When Cron
+// is set it takes precedence over Interval. All durations are in seconds.
+type RefreshConfig struct {
+	ScheduleEnabled bool   `koanf:"schedule_enabled"`
+	Interval        int    `koanf:"interval" validate:"gte=0"`
+	Cron            string `koanf:"cron"`
+	Jitter          int    `koanf:"jitter" validate:"gte=0"`
+	Timeout         int    `koanf:"timeout" validate:"gte=0"`
+	RunOnStartup    bool   `koanf:"run_on_startup"`
+	QueueSize       int    `koanf:"queue_size" validate:"gte=0"`
+	// CountrySource names the country provider: "restcountries" (the v2
+	// API, the default), "restcountriesv3" or "countryfile".
+	CountrySource string `koanf:"country_source"`
+	// RateSources lists exchange-rate providers in priority order. RateMode
+	// is "fallback" (first that succeeds) or "merge" (first that succeeds,
+	// with missing currencies filled from the rest).
+	RateSources []string `koanf:"rate_sources"`
+	RateMode    string   `koanf:"rate_mode" validate:"omitempty,oneof=fallback merge"`
+	// RateHistoryRetentionDays bounds exchange_rate_history; 0 keeps
+	// snapshots forever.
+	RateHistoryRetentionDays int `koanf:"rate_history_retention_days" validate:"gte=0"`
+	// ReconcileMode decides what happens to stored countries missing from
+	// the latest country fetch: "keep" (the default), "inactive" or "delete".
+	ReconcileMode string `koanf:"reconcile_mode" validate:"omitempty,oneof=keep inactive delete"`
+	// GDPMethod selects how estimated_gdp is computed: "random" (the
+	// default, reproducible when GDPSeed is set), "formula" (population
+	// times GDPMultiplier over the rate) or "dataset" (embedded GDP figures,
+	// falling back to the formula).
+	GDPMethod     string  `koanf:"gdp_method" validate:"omitempty,oneof=random formula dataset"`
+	GDPSeed       int64   `koanf:"gdp_seed"`
+	GDPMultiplier float64 `koanf:"gdp_multiplier" validate:"gte=0"`
+	// UpstreamCacheDir holds cached upstream responses used for conditional
+	// fetches. Empty uses cache/upstream.
+	UpstreamCacheDir string `koanf:"upstream_cache_dir"`
+	// ValidationRules lists the checks upstream records must pass before
+	// they are stored: name, population, currency_code and rate. Empty
+	// enables all of them; "none" disables validation.
+	ValidationRules []string `koanf:"validation_rules" validate:"dive,oneof=name population currency_code rate none"`
+	// BaseCurrency is the ISO 4217 code exchange rates are stored against
+	// and estimated GDP is expressed in. Empty uses USD.
+	BaseCurrency string `koanf:"base_currency" validate:"omitempty,len=3,uppercase"`
+}
+
+// SourceConfig configures a single upstream data provider. URL overrides the
+// provider's default endpoint; Path is used by file-based providers. The
+// remaining fields tune the HTTP client; zero values use its defaults.
+type SourceConfig struct {
+	URL           string `koanf:"url"`
+	Path          string `koanf:"path"`
+	Timeout       int    `koanf:"timeout" validate:"gte=0"` // seconds, per attempt
+	MaxRetries    int    `koanf:"max_retries"`              // -1 disables retries
+	BackoffBaseMs int    `koanf:"backoff_base_ms" validate:"gte=0"`
+	BackoffMaxMs  int    `koanf:"backoff_max_ms" validate:"gte=0"`
+	MaxBodyBytes  int64  `koanf:"max_body_bytes" validate:"gte=0"`
+	DisableCache  bool   `koanf:"disable_cache"`
+}
+
 func LoadConfig() (*Config, error) {
 	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
 
@@ -62,6 +122,30 @@ func LoadConfig() (*Config, error) {
 		logger.Fatal().Err(err).Msg("could not load server env variables")
 	}
 
+	// Load REFRESH_* environment variables
+	err = k.Load(env.ProviderWithValue("REFRESH_", ".", func(key, value string) (string, any) {
+		// Transform REFRESH_INTERVAL -> refresh.interval
+		cleanKey := strings.TrimPrefix(key, "REFRESH_")
+		return "refresh." + strings.ToLower(cleanKey), value
+	}), nil)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("could not load refresh env variables")
+	}
+
+	// Load SOURCE_* environment variables
+	err = k.Load(env.ProviderWithValue("SOURCE_", ".", func(key, value string) (string, any) {
+		// Transform SOURCE_RESTCOUNTRIES_URL -> source.restcountries.url
+		cleanKey := strings.ToLower(strings.TrimPrefix(key, "SOURCE_"))
+		name, field, ok := strings.Cut(cleanKey, "_")
+		if !ok {
+			return "", nil
+		}
+		return "source." + name + "." + field, value
+	}), nil)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("could not load source env variables")
+	}
+
 	mainConfig := &Config{}
 
 	err = k.Unmarshal("", mainConfig)
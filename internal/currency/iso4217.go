@@ -0,0 +1,36 @@
+// Package currency holds reference data about currency codes.
+package currency
+
+import "strings"
+
+// isoCodes lists the active ISO 4217 alphabetic codes, followed by
+// superseded ones that older upstream datasets still return.
+const isoCodes = `
+AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB
+BOV BRL BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUC
+CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF
+GTQ GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF
+KPW KRW KWD KYD KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU
+MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR
+PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SLL SOS SRD SSP
+STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD USN UYI UYU
+UYW UZS VED VES VND VUV WST XAF XAG XAU XBA XBB XBC XBD XCD XCG XDR XOF XPD
+XPF XPT XSU XTS XUA XXX YER ZAR ZMW ZWG ZWL
+
+BYR EEK HRK LTL LVL MRO STD VEF ZMK
+`
+
+var known = func() map[string]bool {
+	codes := strings.Fields(isoCodes)
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
+}()
+
+// IsISO4217 reports whether code is an ISO 4217 alphabetic currency code.
+// The comparison is case-sensitive: codes are upper case.
+func IsISO4217(code string) bool {
+	return known[code]
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS refresh_jobs;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS refresh_jobs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    status VARCHAR(16) NOT NULL,
+    trigger_type VARCHAR(16) NOT NULL,
+    queued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP NULL,
+    finished_at TIMESTAMP NULL,
+    duration_ms BIGINT,
+    countries_processed INT,
+    error_message VARCHAR(256),
+    error_details TEXT,
+    KEY idx_refresh_jobs_status (status)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS country_currencies (
+    country_id INT NOT NULL,
+    currency_code VARCHAR(20) NOT NULL,
+    currency_name VARCHAR(256),
+    currency_symbol VARCHAR(32),
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    position INT NOT NULL DEFAULT 0,
+    exchange_rate DECIMAL(15, 6),
+    PRIMARY KEY (country_id, currency_code),
+    KEY idx_country_currencies_currency_code (currency_code),
+    CONSTRAINT fk_country_currencies_country
+        FOREIGN KEY (country_id) REFERENCES countries (id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Backfill the single currency we stored before this table existed.
+INSERT IGNORE INTO country_currencies (country_id, currency_code, is_primary, position, exchange_rate)
+SELECT id, currency_code, TRUE, 0, exchange_rate
+FROM countries
+WHERE currency_code IS NOT NULL;
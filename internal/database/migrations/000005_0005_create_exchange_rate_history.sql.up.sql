@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS exchange_rate_history (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    currency_code VARCHAR(20) NOT NULL,
+    base_code VARCHAR(20) NOT NULL,
+    rate DECIMAL(15, 6) NOT NULL,
+    rate_timestamp TIMESTAMP NOT NULL,
+    recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_exchange_rate_history (currency_code, base_code, rate_timestamp),
+    KEY idx_exchange_rate_history_rate_timestamp (rate_timestamp)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE exchange_rate_history DROP COLUMN rate_source;
+ALTER TABLE country_currencies DROP COLUMN rate_source;
+ALTER TABLE countries DROP COLUMN rate_source;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE countries ADD COLUMN rate_source VARCHAR(64) NULL;
+ALTER TABLE country_currencies ADD COLUMN rate_source VARCHAR(64) NULL;
+ALTER TABLE exchange_rate_history ADD COLUMN rate_source VARCHAR(64) NULL;
@@ -0,0 +1,10 @@
+ALTER TABLE refresh_jobs
+    DROP COLUMN rates_refreshed,
+    DROP COLUMN countries_refreshed;
+
+ALTER TABLE app_status
+    DROP COLUMN rates_stale,
+    DROP COLUMN rates_refreshed_at,
+    DROP COLUMN countries_refreshed_at;
+
+ALTER TABLE countries DROP COLUMN rates_stale;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE countries ADD COLUMN rates_stale BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE app_status
+    ADD COLUMN countries_refreshed_at TIMESTAMP NULL,
+    ADD COLUMN rates_refreshed_at TIMESTAMP NULL,
+    ADD COLUMN rates_stale BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Every refresh before this migration updated both parts.
+UPDATE app_status
+SET countries_refreshed_at = last_refreshed_at,
+    rates_refreshed_at = last_refreshed_at;
+
+ALTER TABLE refresh_jobs
+    ADD COLUMN countries_refreshed BOOLEAN NULL,
+    ADD COLUMN rates_refreshed BOOLEAN NULL;
@@ -0,0 +1 @@
+ALTER TABLE countries DROP COLUMN gdp_method;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE countries ADD COLUMN gdp_method VARCHAR(16) NULL;
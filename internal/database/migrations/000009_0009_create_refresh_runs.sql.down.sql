@@ -0,0 +1 @@
+DROP TABLE IF EXISTS refresh_runs;
\ No newline at end of file
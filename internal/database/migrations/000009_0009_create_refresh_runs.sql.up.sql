@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS refresh_runs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    job_id BIGINT NULL,
+    trigger_type VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP NULL,
+    duration_ms BIGINT,
+    country_source VARCHAR(128),
+    rate_source VARCHAR(128),
+    countries_latency_ms BIGINT,
+    rates_latency_ms BIGINT,
+    countries_refreshed BOOLEAN NULL,
+    rates_refreshed BOOLEAN NULL,
+    countries_inserted INT,
+    countries_updated INT,
+    countries_unchanged INT,
+    error_message VARCHAR(256),
+    error_details TEXT,
+    KEY idx_refresh_runs_job_id (job_id),
+    KEY idx_refresh_runs_started_at (started_at)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS refresh_run_changes;
+ALTER TABLE refresh_runs DROP COLUMN countries_removed;
\ No newline at end of file
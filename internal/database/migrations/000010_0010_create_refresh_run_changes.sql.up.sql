@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS refresh_run_changes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    run_id BIGINT NOT NULL,
+    country_name VARCHAR(256) NOT NULL,
+    change_type VARCHAR(16) NOT NULL,
+    field VARCHAR(64),
+    old_value TEXT,
+    new_value TEXT,
+    delta DOUBLE,
+    KEY idx_refresh_run_changes_run_id (run_id),
+    CONSTRAINT fk_refresh_run_changes_run
+        FOREIGN KEY (run_id) REFERENCES refresh_runs (id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE refresh_runs ADD COLUMN countries_removed INT AFTER countries_unchanged;
@@ -0,0 +1,5 @@
+DROP INDEX idx_countries_status ON countries;
+
+ALTER TABLE countries
+    DROP COLUMN inactive_since,
+    DROP COLUMN status;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE countries
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active',
+    ADD COLUMN inactive_since TIMESTAMP NULL;
+
+CREATE INDEX idx_countries_status ON countries (status);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS deleted_countries;
\ No newline at end of file
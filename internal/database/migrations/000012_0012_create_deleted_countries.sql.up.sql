@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS deleted_countries (
+    name VARCHAR(256) NOT NULL PRIMARY KEY,
+    deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS country_overrides;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS country_overrides (
+    country_name VARCHAR(256) NOT NULL,
+    field VARCHAR(64) NOT NULL,
+    value TEXT,
+    author VARCHAR(128) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (country_name, field)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE refresh_runs
+    DROP COLUMN rates_not_modified,
+    DROP COLUMN countries_not_modified;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE refresh_runs
+    ADD COLUMN countries_not_modified BOOLEAN NULL AFTER rates_latency_ms,
+    ADD COLUMN rates_not_modified BOOLEAN NULL AFTER countries_not_modified;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS quarantined_countries;
+ALTER TABLE refresh_runs DROP COLUMN countries_quarantined;
\ No newline at end of file
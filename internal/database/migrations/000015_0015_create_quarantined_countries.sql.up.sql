@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS quarantined_countries (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    run_id BIGINT NOT NULL,
+    country_name VARCHAR(256) NOT NULL,
+    rule VARCHAR(32) NOT NULL,
+    reason TEXT NOT NULL,
+    record TEXT,
+    quarantined_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_quarantined_countries_run_id (run_id),
+    CONSTRAINT fk_quarantined_countries_run
+        FOREIGN KEY (run_id) REFERENCES refresh_runs (id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE refresh_runs ADD COLUMN countries_quarantined INT AFTER countries_removed;
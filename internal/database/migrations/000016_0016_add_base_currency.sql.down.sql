@@ -0,0 +1 @@
+ALTER TABLE app_status DROP COLUMN base_currency;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE app_status ADD COLUMN base_currency VARCHAR(20) NOT NULL DEFAULT 'USD' AFTER rates_stale;
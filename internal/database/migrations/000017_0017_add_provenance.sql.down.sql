@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS upstream_sources;
+
+ALTER TABLE countries
+    DROP COLUMN country_source,
+    DROP COLUMN country_source_url,
+    DROP COLUMN countries_fetched_at,
+    DROP COLUMN rate_source_url,
+    DROP COLUMN rates_updated_at,
+    DROP COLUMN rates_fetched_at;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE countries
+    ADD COLUMN country_source VARCHAR(64) NULL,
+    ADD COLUMN country_source_url VARCHAR(512) NULL,
+    ADD COLUMN countries_fetched_at TIMESTAMP NULL,
+    ADD COLUMN rate_source_url VARCHAR(512) NULL,
+    ADD COLUMN rates_updated_at TIMESTAMP NULL,
+    ADD COLUMN rates_fetched_at TIMESTAMP NULL;
+
+CREATE TABLE IF NOT EXISTS upstream_sources (
+    name VARCHAR(64) NOT NULL PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL,
+    url VARCHAR(512),
+    data_updated_at TIMESTAMP NULL,
+    fetched_at TIMESTAMP NOT NULL
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS country_borders;
+DROP TABLE IF EXISTS country_calling_codes;
+DROP TABLE IF EXISTS country_timezones;
+DROP TABLE IF EXISTS country_languages;
+
+ALTER TABLE countries
+    DROP KEY idx_countries_alpha3_code,
+    DROP KEY idx_countries_alpha2_code,
+    DROP COLUMN area,
+    DROP COLUMN subregion,
+    DROP COLUMN alpha3_code,
+    DROP COLUMN alpha2_code;
\ No newline at end of file
@@ -0,0 +1,46 @@
+ALTER TABLE countries
+    ADD COLUMN alpha2_code CHAR(2) NULL,
+    ADD COLUMN alpha3_code CHAR(3) NULL,
+    ADD COLUMN subregion VARCHAR(256) NULL,
+    ADD COLUMN area DECIMAL(15, 2) NULL,
+    ADD KEY idx_countries_alpha2_code (alpha2_code),
+    ADD KEY idx_countries_alpha3_code (alpha3_code);
+
+CREATE TABLE IF NOT EXISTS country_languages (
+    country_id INT NOT NULL,
+    position INT NOT NULL,
+    language_code VARCHAR(16) NOT NULL,
+    language_name VARCHAR(128),
+    PRIMARY KEY (country_id, position),
+    KEY idx_country_languages_language_code (language_code),
+    CONSTRAINT fk_country_languages_country
+        FOREIGN KEY (country_id) REFERENCES countries (id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS country_timezones (
+    country_id INT NOT NULL,
+    position INT NOT NULL,
+    timezone VARCHAR(64) NOT NULL,
+    PRIMARY KEY (country_id, position),
+    CONSTRAINT fk_country_timezones_country
+        FOREIGN KEY (country_id) REFERENCES countries (id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS country_calling_codes (
+    country_id INT NOT NULL,
+    position INT NOT NULL,
+    calling_code VARCHAR(16) NOT NULL,
+    PRIMARY KEY (country_id, position),
+    CONSTRAINT fk_country_calling_codes_country
+        FOREIGN KEY (country_id) REFERENCES countries (id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS country_borders (
+    country_id INT NOT NULL,
+    position INT NOT NULL,
+    border_code CHAR(3) NOT NULL,
+    PRIMARY KEY (country_id, position),
+    KEY idx_country_borders_border_code (border_code),
+    CONSTRAINT fk_country_borders_country
+        FOREIGN KEY (country_id) REFERENCES countries (id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
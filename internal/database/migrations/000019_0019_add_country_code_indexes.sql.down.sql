@@ -0,0 +1,7 @@
+ALTER TABLE countries
+    DROP KEY uq_countries_numeric_code,
+    DROP KEY uq_countries_alpha3_code,
+    DROP KEY uq_countries_alpha2_code,
+    ADD KEY idx_countries_alpha2_code (alpha2_code),
+    ADD KEY idx_countries_alpha3_code (alpha3_code),
+    DROP COLUMN numeric_code;
\ No newline at end of file
@@ -0,0 +1,28 @@
+-- Keep the code on the oldest row if earlier refreshes stored duplicates.
+UPDATE countries c
+JOIN (
+    SELECT alpha2_code, MIN(id) AS id
+    FROM countries
+    WHERE alpha2_code IS NOT NULL
+    GROUP BY alpha2_code
+    HAVING COUNT(*) > 1
+) d ON d.alpha2_code = c.alpha2_code AND d.id <> c.id
+SET c.alpha2_code = NULL;
+
+UPDATE countries c
+JOIN (
+    SELECT alpha3_code, MIN(id) AS id
+    FROM countries
+    WHERE alpha3_code IS NOT NULL
+    GROUP BY alpha3_code
+    HAVING COUNT(*) > 1
+) d ON d.alpha3_code = c.alpha3_code AND d.id <> c.id
+SET c.alpha3_code = NULL;
+
+ALTER TABLE countries
+    ADD COLUMN numeric_code CHAR(3) NULL,
+    DROP KEY idx_countries_alpha2_code,
+    DROP KEY idx_countries_alpha3_code,
+    ADD UNIQUE KEY uq_countries_alpha2_code (alpha2_code),
+    ADD UNIQUE KEY uq_countries_alpha3_code (alpha3_code),
+    ADD UNIQUE KEY uq_countries_numeric_code (numeric_code);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS country_aliases;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS country_aliases (
+    alias VARCHAR(256) NOT NULL PRIMARY KEY,
+    country_name VARCHAR(256) NOT NULL,
+    source VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY idx_country_aliases_country_name (country_name)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,4 @@
+ALTER TABLE app_status
+    DROP COLUMN countries_restored_at,
+    DROP COLUMN validation_rules,
+    DROP COLUMN countries_fetched_at;
\ No newline at end of file
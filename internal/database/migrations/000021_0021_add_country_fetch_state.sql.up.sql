@@ -0,0 +1,4 @@
+ALTER TABLE app_status
+    ADD COLUMN countries_fetched_at TIMESTAMP NULL,
+    ADD COLUMN validation_rules VARCHAR(255) NULL,
+    ADD COLUMN countries_restored_at TIMESTAMP NULL;
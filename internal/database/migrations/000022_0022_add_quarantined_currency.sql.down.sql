@@ -0,0 +1 @@
+ALTER TABLE quarantined_countries DROP COLUMN currency_code;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE quarantined_countries ADD COLUMN currency_code VARCHAR(20) NULL AFTER country_name;
@@ -0,0 +1 @@
+ALTER TABLE deleted_countries DROP COLUMN record;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE deleted_countries ADD COLUMN record MEDIUMTEXT NULL;
@@ -1,5 +1,45 @@
 package errs
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrNotFound = errors.New("string not found")
+
+var ErrQueueFull = errors.New("refresh queue is full")
+
+// ErrRunnerStopped is returned when a refresh job is requested after the job
+// runner began shutting down.
+var ErrRunnerStopped = errors.New("refresh job runner is stopped")
+
+// ErrJobClaimed is returned when a refresh job was taken by another worker,
+// possibly on another instance, before this one could start it.
+var ErrJobClaimed = errors.New("refresh job already claimed")
+
+// ErrSnapshotInvalid is returned when a snapshot cannot be restored as given.
+var ErrSnapshotInvalid = errors.New("invalid snapshot")
+
+// UpstreamError is returned when one or more external data sources could not
+// be fetched or returned unusable data.
+type UpstreamError struct {
+	Details string
+}
+
+func (e *UpstreamError) Error() string {
+	return "external data source unavailable: " + e.Details
+}
+
+// RefreshInProgressError is returned when another refresh, possibly on
+// another instance, holds the refresh lock. RunID is 0 if that refresh has
+// not recorded its run yet.
+type RefreshInProgressError struct {
+	RunID int64
+}
+
+func (e *RefreshInProgressError) Error() string {
+	if e.RunID == 0 {
+		return "refresh already in progress"
+	}
+	return fmt.Sprintf("refresh already in progress: run %d", e.RunID)
+}
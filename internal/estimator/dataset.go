@@ -0,0 +1,49 @@
+package estimator
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//go:embed data/gdp.json
+var gdpDataset []byte
+
+type gdpDatasetFile struct {
+	Year   int                `json:"year"`
+	Source string             `json:"source"`
+	GDP    map[string]float64 `json:"gdp"`
+}
+
+// DatasetEstimator looks countries up in an embedded table of nominal GDP in
+// US dollars, keyed by ISO 3166-1 alpha-3 code so it does not depend on how
+// the country source spells names, and converts the figure to the base
+// currency. Countries missing from the table, or any country when
+// the dollar rate is unknown, are passed to the fallback estimator, if any.
+type DatasetEstimator struct {
+	gdp      map[string]float64
+	fallback GDPEstimator
+}
+
+func NewDatasetEstimator(fallback GDPEstimator) (*DatasetEstimator, error) {
+	var file gdpDatasetFile
+	if err := json.Unmarshal(gdpDataset, &file); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal gdp dataset: %w", err)
+	}
+	return &DatasetEstimator{gdp: file.GDP, fallback: fallback}, nil
+}
+
+func (e *DatasetEstimator) Name() string {
+	return Dataset
+}
+
+func (e *DatasetEstimator) Estimate(in Input) (Estimate, bool) {
+	if value, ok := e.gdp[strings.ToUpper(in.Alpha3Code)]; ok && in.USDRate > 0 {
+		return Estimate{Value: value / in.USDRate, Method: Dataset}, true
+	}
+	if e.fallback == nil {
+		return Estimate{}, false
+	}
+	return e.fallback.Estimate(in)
+}
@@ -0,0 +1,54 @@
+package estimator
+
+import "testing"
+
+func TestDatasetEstimator(t *testing.T) {
+	e, err := NewDatasetEstimator(NewFormulaEstimator(1000))
+	if err != nil {
+		t.Fatalf("NewDatasetEstimator: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		in         Input
+		wantValue  float64
+		wantMethod string
+	}{
+		{
+			name:       "looked up by alpha-3 whatever the display name",
+			in:         Input{Name: "united kingdom", Alpha3Code: "GBR", Population: 1, ExchangeRate: 1, HasRate: true, USDRate: 1},
+			wantValue:  3340000000000,
+			wantMethod: Dataset,
+		},
+		{
+			name:       "alpha-3 matched case-insensitively and converted to the base",
+			in:         Input{Name: "united states", Alpha3Code: "usa", Population: 1, ExchangeRate: 1, HasRate: true, USDRate: 0.5},
+			wantValue:  27360900000000 / 0.5,
+			wantMethod: Dataset,
+		},
+		{
+			name:       "missing country falls back",
+			in:         Input{Name: "atlantis", Alpha3Code: "ATL", Population: 10, ExchangeRate: 2, HasRate: true, USDRate: 1},
+			wantValue:  10 * 1000 / 2,
+			wantMethod: Formula,
+		},
+		{
+			name:       "unknown dollar rate falls back",
+			in:         Input{Name: "japan", Alpha3Code: "JPN", Population: 10, ExchangeRate: 2, HasRate: true},
+			wantValue:  10 * 1000 / 2,
+			wantMethod: Formula,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := e.Estimate(tt.in)
+			if !ok {
+				t.Fatal("Estimate returned no estimate")
+			}
+			if got.Value != tt.wantValue || got.Method != tt.wantMethod {
+				t.Errorf("Estimate = %+v, want {Value:%v Method:%s}", got, tt.wantValue, tt.wantMethod)
+			}
+		})
+	}
+}
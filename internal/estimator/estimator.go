@@ -0,0 +1,77 @@
+package estimator
+
+import (
+	"fmt"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/model"
+)
+
+const (
+	Random  = "random"
+	Formula = "formula"
+	Dataset = model.GDPMethodDataset
+
+	// The random estimator draws a per-capita multiplier from this range;
+	// the formula estimator defaults to its midpoint.
+	minMultiplier     = 1000.0
+	maxMultiplier     = 2000.0
+	defaultMultiplier = (minMultiplier + maxMultiplier) / 2
+)
+
+// Input is what an estimator knows about a country. Alpha3Code is its ISO
+// 3166-1 alpha-3 code, if known. ExchangeRate is the primary currency's rate
+// against the base currency; HasRate is false when no rate is known. USDRate
+// is the value of one unit of the base currency in US dollars, or 0 when
+// unknown.
+type Input struct {
+	Name         string
+	Alpha3Code   string
+	Population   int64
+	ExchangeRate float64
+	HasRate      bool
+	USDRate      float64
+}
+
+// Estimate is an estimated GDP in the base currency and the method that
+// produced it.
+type Estimate struct {
+	Value  float64
+	Method string
+}
+
+// GDPEstimator estimates a country's GDP. It returns false when it has no
+// estimate for the country.
+type GDPEstimator interface {
+	Name() string
+	Estimate(in Input) (Estimate, bool)
+}
+
+// New builds the estimator selected by cfg.GDPMethod. An empty method keeps
+// the original random behaviour.
+func New(cfg config.RefreshConfig) (GDPEstimator, error) {
+	multiplier := cfg.GDPMultiplier
+	if multiplier <= 0 {
+		multiplier = defaultMultiplier
+	}
+
+	switch cfg.GDPMethod {
+	case "", Random:
+		return NewRandomEstimator(cfg.GDPSeed), nil
+	case Formula:
+		return NewFormulaEstimator(multiplier), nil
+	case Dataset:
+		return NewDatasetEstimator(NewFormulaEstimator(multiplier))
+	default:
+		return nil, fmt.Errorf("unknown gdp method %q", cfg.GDPMethod)
+	}
+}
+
+// perCapita turns a per-capita figure in local currency into a GDP in the
+// base currency.
+func perCapita(in Input, multiplier float64) (float64, bool) {
+	if !in.HasRate || in.ExchangeRate == 0 {
+		return 0, false
+	}
+	return float64(in.Population) * multiplier / in.ExchangeRate, true
+}
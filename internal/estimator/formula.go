@@ -0,0 +1,23 @@
+package estimator
+
+// FormulaEstimator multiplies population by a fixed per-capita figure, so
+// the estimate only moves when population or the exchange rate does.
+type FormulaEstimator struct {
+	multiplier float64
+}
+
+func NewFormulaEstimator(multiplier float64) *FormulaEstimator {
+	return &FormulaEstimator{multiplier: multiplier}
+}
+
+func (e *FormulaEstimator) Name() string {
+	return Formula
+}
+
+func (e *FormulaEstimator) Estimate(in Input) (Estimate, bool) {
+	value, ok := perCapita(in, e.multiplier)
+	if !ok {
+		return Estimate{}, false
+	}
+	return Estimate{Value: value, Method: Formula}, true
+}
@@ -0,0 +1,40 @@
+package estimator
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// RandomEstimator multiplies population by a random per-capita figure. With
+// a non-zero seed the figure is derived from the seed and the country name,
+// so a country keeps the same multiplier across refreshes.
+type RandomEstimator struct {
+	seed int64
+}
+
+func NewRandomEstimator(seed int64) *RandomEstimator {
+	return &RandomEstimator{seed: seed}
+}
+
+func (e *RandomEstimator) Name() string {
+	return Random
+}
+
+func (e *RandomEstimator) Estimate(in Input) (Estimate, bool) {
+	value, ok := perCapita(in, e.multiplier(in.Name))
+	if !ok {
+		return Estimate{}, false
+	}
+	return Estimate{Value: value, Method: Random}, true
+}
+
+func (e *RandomEstimator) multiplier(name string) float64 {
+	if e.seed == 0 {
+		return minMultiplier + rand.Float64()*(maxMultiplier-minMultiplier)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	rng := rand.New(rand.NewSource(e.seed ^ int64(h.Sum64())))
+	return minMultiplier + rng.Float64()*(maxMultiplier-minMultiplier)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/util"
+)
+
+// maxSuggestions caps the names suggested when a country is not found.
+const maxSuggestions = 3
+
+func (h *ForexHandler) HandleListCountryAliases(w http.ResponseWriter, r *http.Request) {
+	country := model.NormalizeCountryName(r.URL.Query().Get("country"))
+
+	aliases, err := h.repo.GetCountryAliases(r.Context(), country)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to Fetch country aliases")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	util.WriteJsonSuccess(w, http.StatusOK, model.ToCountryAliasResponses(aliases))
+}
+
+// HandlePutCountryAlias points an alias at a country by hand. The country
+// may be given by name, alias or ISO code.
+func (h *ForexHandler) HandlePutCountryAlias(w http.ResponseWriter, r *http.Request) {
+	alias := model.NormalizeCountryName(chi.URLParam(r, "alias"))
+
+	var req model.CountryAliasRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPatchBodyBytes)).Decode(&req); err != nil {
+		details := err.Error()
+		util.WriteJsonError(w, http.StatusBadRequest, "Invalid request body", &details)
+		return
+	}
+
+	req.Country = strings.TrimSpace(req.Country)
+	switch {
+	case alias == "":
+		details := "alias must not be blank"
+		util.WriteJsonError(w, http.StatusBadRequest, "Validation failed", &details)
+		return
+	case req.Country == "":
+		details := "country is required"
+		util.WriteJsonError(w, http.StatusBadRequest, "Validation failed", &details)
+		return
+	}
+
+	country, err := h.repo.GetCountryByName(r.Context(), req.Country)
+	if errors.Is(err, errs.ErrNotFound) && model.CountryCodeKind(req.Country) != "" {
+		country, err = h.repo.GetCountryByCode(r.Context(), req.Country)
+	}
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.writeCountryNotFound(w, r, req.Country)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to Fetch Countries")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	saved, err := h.repo.SetCountryAlias(r.Context(), alias, country.Name)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to save country alias")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	util.WriteJsonSuccess(w, http.StatusOK, saved.ToResponse())
+}
+
+// HandleDeleteCountryAlias deletes an alias for good: upstream spellings
+// that come back on a refresh stay deleted until the alias is set by hand.
+func (h *ForexHandler) HandleDeleteCountryAlias(w http.ResponseWriter, r *http.Request) {
+	alias := chi.URLParam(r, "alias")
+
+	if err := h.repo.DeleteCountryAlias(r.Context(), alias); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			util.WriteJsonError(w, http.StatusNotFound, "Alias not found", nil)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to delete country alias")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeCountryNotFound answers 404 for a country lookup, suggesting the
+// countries whose names or aliases are closest to query.
+func (h *ForexHandler) writeCountryNotFound(w http.ResponseWriter, r *http.Request, query string) {
+	limit := max(len([]rune(model.NormalizeCountryName(query)))/3, 2)
+	candidates, err := h.repo.GetCountryNameCandidates(r.Context())
+	h.writeNotFoundSuggesting(w, query, candidates, limit, err)
+}
+
+// writeCountryCodeNotFound answers 404 for a lookup by ISO code, suggesting
+// the countries whose code of the same kind is one edit away.
+func (h *ForexHandler) writeCountryCodeNotFound(w http.ResponseWriter, r *http.Request, code string) {
+	candidates, err := h.repo.GetCountryCodeCandidates(r.Context(), model.CountryCodeKind(code))
+	h.writeNotFoundSuggesting(w, code, candidates, 1, err)
+}
+
+func (h *ForexHandler) writeNotFoundSuggesting(w http.ResponseWriter, query string, candidates map[string]string, limit int, err error) {
+	suggestions := []string{}
+	if err != nil {
+		// The 404 stands on its own; suggestions are a courtesy.
+		h.logger.Error().Err(err).Msg("Failed to suggest countries")
+	} else {
+		suggestions = rankSuggestions(query, candidates, limit)
+	}
+
+	util.WriteJsonErrorWith(w, http.StatusNotFound, "Country not found", nil, model.CountryNotFoundResponse{
+		Suggestions: suggestions,
+	})
+}
+
+// rankSuggestions returns up to maxSuggestions country names ranked by the
+// edit distance between query and the closest spelling that maps to each
+// name in candidates, compared as normalized names. Spellings more than
+// limit edits away are not suggested; ties go in name order.
+func rankSuggestions(query string, candidates map[string]string, limit int) []string {
+	query = model.NormalizeCountryName(query)
+	distances := map[string]int{}
+	for spelling, name := range candidates {
+		d := util.Levenshtein(query, model.NormalizeCountryName(spelling))
+		if d > limit {
+			continue
+		}
+		if best, ok := distances[name]; !ok || d < best {
+			distances[name] = d
+		}
+	}
+
+	names := make([]string, 0, len(distances))
+	for name := range distances {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if distances[names[i]] != distances[names[j]] {
+			return distances[names[i]] < distances[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > maxSuggestions {
+		names = names[:maxSuggestions]
+	}
+	return names
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRankSuggestions(t *testing.T) {
+	names := map[string]string{
+		"Nigeria":                  "Nigeria",
+		"Niger":                    "Niger",
+		"Algeria":                  "Algeria",
+		"United Kingdom":           "United Kingdom",
+		"uk":                       "United Kingdom",
+		"Great Britain":            "United Kingdom",
+		"United States":            "United States",
+		"united states of america": "United States",
+		"Côte d'Ivoire":            "Côte d'Ivoire",
+	}
+	codes := map[string]string{
+		"FRA": "France",
+		"FRO": "Faroe Islands",
+		"GRC": "Greece",
+		"DEU": "Germany",
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		candidates map[string]string
+		limit      int
+		want       []string
+	}{
+		{
+			name:       "closest first",
+			query:      "Nigeia",
+			candidates: names,
+			limit:      2,
+			want:       []string{"Nigeria", "Niger"},
+		},
+		{
+			name:       "ties in name order",
+			query:      "Nigera",
+			candidates: names,
+			limit:      2,
+			want:       []string{"Niger", "Nigeria"},
+		},
+		{
+			name:       "alias counts for its country once",
+			query:      "Untied Kingdom",
+			candidates: names,
+			limit:      4,
+			want:       []string{"United Kingdom"},
+		},
+		{
+			name:       "case and spacing are ignored",
+			query:      "  UNITED   states ",
+			candidates: names,
+			limit:      2,
+			want:       []string{"United States"},
+		},
+		{
+			name:       "curly apostrophe matches",
+			query:      "Côte d’Ivoir",
+			candidates: names,
+			limit:      2,
+			want:       []string{"Côte d'Ivoire"},
+		},
+		{
+			name:       "nothing within the limit",
+			query:      "Atlantis",
+			candidates: names,
+			limit:      2,
+			want:       []string{},
+		},
+		{
+			name:       "codes one edit away",
+			query:      "fre",
+			candidates: codes,
+			limit:      1,
+			want:       []string{"Faroe Islands", "France"},
+		},
+		{
+			name:       "capped at maxSuggestions",
+			query:      "FRC",
+			candidates: codes,
+			limit:      3,
+			want:       []string{"Faroe Islands", "France", "Greece"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rankSuggestions(tt.query, tt.candidates, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rankSuggestions(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
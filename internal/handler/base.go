@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/util"
+)
+
+// baseParam reads ?base= and returns the currency responses should be
+// quoted in along with the value of one unit of the stored base currency in
+// it. Without the parameter the stored base is used as is. On failure the
+// error response has been written and ok is false.
+func (h *ForexHandler) baseParam(w http.ResponseWriter, r *http.Request) (base string, rate float64, ok bool) {
+	stored, err := h.repo.GetBaseCurrency(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to Fetch base currency")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return "", 0, false
+	}
+
+	base = strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("base")))
+	if base == "" || base == stored {
+		return stored, 1, true
+	}
+
+	rate, err = h.repo.GetStoredRate(r.Context(), base)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			details := "no exchange rate is stored for base " + base
+			util.WriteJsonError(w, http.StatusBadRequest, "Invalid query parameter", &details)
+			return "", 0, false
+		}
+		h.logger.Error().Err(err).Msg("Failed to Fetch exchange rate")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return "", 0, false
+	}
+	return base, rate, true
+}
@@ -1,171 +1,133 @@
 package handler
 
 import (
-	"context"
-	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
-	"sync"
-	"time"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/justinndidit/forex/internal/database"
 	"github.com/justinndidit/forex/internal/errs"
 	"github.com/justinndidit/forex/internal/model"
 	"github.com/justinndidit/forex/internal/repository"
+	"github.com/justinndidit/forex/internal/service"
 
 	"github.com/justinndidit/forex/internal/util"
 	"github.com/rs/zerolog"
 )
 
 type ForexHandler struct {
-	logger *zerolog.Logger
-	db     *database.Database
-	repo   *repository.ForexRepository
-	imgGen *util.ImageService
+	logger    *zerolog.Logger
+	db        *database.Database
+	repo      *repository.ForexRepository
+	jobs      *service.JobRunner
+	snapshots *service.SnapshotService
 }
 
-func NewForexHandler(logger *zerolog.Logger, db *database.Database, repo *repository.ForexRepository, imgGen *util.ImageService) *ForexHandler {
+func NewForexHandler(logger *zerolog.Logger, db *database.Database, repo *repository.ForexRepository, jobs *service.JobRunner, snapshots *service.SnapshotService) *ForexHandler {
 	return &ForexHandler{
-		logger: logger,
-		db:     db,
-		repo:   repo,
-		imgGen: imgGen,
+		logger:    logger,
+		db:        db,
+		repo:      repo,
+		jobs:      jobs,
+		snapshots: snapshots,
 	}
 }
 
+// HandleRefresh queues a refresh. If one is already running on any instance
+// it answers 409 with the running refresh, or with ?join=true hands back
+// that refresh's run instead of queueing another.
 func (h *ForexHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
-	const countriesURL = "https://restcountries.com/v2/all?fields=name,capital,region,population,flag,currencies"
-	const ratesURL = "https://open.er-api.com/v6/latest/USD"
-	ctx := r.Context()
-
-	var wg sync.WaitGroup
-	resultChan := make(chan util.FetchResult, 2)
-	wg.Add(2)
-	go util.FetchData(countriesURL, &wg, resultChan)
-	go util.FetchData(ratesURL, &wg, resultChan)
-	wg.Wait()
-	close(resultChan)
-
-	var countriesList []model.Country
-	var exchangeData model.ExchangeRates
-	var failedURLs []string
-
-	for result := range resultChan {
-		if result.Err != nil {
-			h.logger.Error().Err(result.Err).Msg("Failed to fetch from API: " + result.URL)
-			failedURLs = append(failedURLs, result.URL)
-			continue
-		}
-
-		switch result.URL {
-		case countriesURL:
-			if err := json.Unmarshal(result.Body, &countriesList); err != nil {
-				h.logger.Error().Err(err).Msg("Failed to unmarshal countries data")
-				failedURLs = append(failedURLs, "countries API (unmarshal fail)")
-			}
-		case ratesURL:
-			if err := json.Unmarshal(result.Body, &exchangeData); err != nil {
-				h.logger.Error().Err(err).Msg("Failed to unmarshal exchange rate data")
-				failedURLs = append(failedURLs, "exchange rate API (unmarshal fail)")
-			}
+	join := false
+	if v := r.URL.Query().Get("join"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			details := "join must be true or false"
+			util.WriteJsonError(w, http.StatusBadRequest, "Invalid query parameter", &details)
+			return
 		}
+		join = parsed
 	}
 
-	if len(failedURLs) > 0 {
-		details := fmt.Sprintf("Could not fetch data from: %s", strings.Join(failedURLs, ", "))
-		util.WriteJsonError(w, http.StatusServiceUnavailable, "External data source unavailable", &details)
+	inProgress, err := h.jobs.InProgress(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to check for a running refresh")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
 		return
 	}
-
-	if len(countriesList) == 0 || exchangeData.Rates == nil {
-		details := "API returned empty or invalid data"
-		h.logger.Error().Msg(details)
-		util.WriteJsonError(w, http.StatusServiceUnavailable, "External data source unavailable", &details)
+	if inProgress != nil {
+		h.writeRefreshInProgress(w, r, inProgress, join)
 		return
 	}
-	rates := exchangeData.Rates
-	refreshTime := time.Now()
-	var rowsToInsert []model.CountryDBRow
-
-	for _, country := range countriesList {
-		dbRow := model.CountryDBRow{
-			// --- These fields are OK ---
-			Name:       strings.ToLower(country.Name),
-			Population: country.Population,
-
-			// --- Corrected fields ---
-			Capital: sql.NullString{
-				String: strings.ToLower(country.Capital),
-				Valid:  country.Capital != "", // Set to 'true' only if it's not empty
-			},
-			Region: sql.NullString{
-				String: strings.ToLower(country.Region),
-				Valid:  country.Region != "",
-			},
-			FlagURL: sql.NullString{
-				String: country.FlagURL, // No need to lowercase a URL
-				Valid:  country.FlagURL != "",
-			},
-			LastRefreshedAt: sql.NullTime{
-				Time:  refreshTime,
-				Valid: true, // We always set this
-			},
-		}
-		if len(country.Currencies) > 0 {
-			code := country.Currencies[0].Code
-			dbRow.CurrencyCode = sql.NullString{String: code, Valid: true}
-
-			if rate, ok := rates[code]; ok {
-				dbRow.ExchangeRate = sql.NullFloat64{Float64: rate, Valid: true}
-				randomMultiplier := util.RandFloatRange()
-				gdp := (float64(country.Population) * randomMultiplier) / rate
-				dbRow.EstimatedGDP = sql.NullFloat64{Float64: gdp, Valid: true}
-			}
-
-		} else {
-			dbRow.EstimatedGDP = sql.NullFloat64{Float64: 0, Valid: true}
-		}
-
-		rowsToInsert = append(rowsToInsert, dbRow)
-	}
 
-	err := h.repo.UpdateCountries(ctx, rowsToInsert, refreshTime)
+	job, err := h.jobs.Enqueue(r.Context(), model.RefreshTriggerManual)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to update database")
+		if errors.Is(err, errs.ErrQueueFull) {
+			util.WriteJsonError(w, http.StatusServiceUnavailable, "Refresh queue is full", nil)
+			return
+		}
+		if errors.Is(err, errs.ErrRunnerStopped) {
+			util.WriteJsonError(w, http.StatusServiceUnavailable, "Server is shutting down", nil)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to enqueue refresh job")
 		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
 		return
 	}
-	go h.generateAndLogSummary(context.Background(), refreshTime)
 
-	util.WriteJsonSuccess(w, http.StatusOK, map[string]string{"message": "Database refresh successfully initiated"})
+	w.Header().Set("Location", fmt.Sprintf("/refresh/jobs/%d", job.ID))
+	util.WriteJsonSuccess(w, http.StatusAccepted, job.ToResponse())
 }
 
-func (h *ForexHandler) generateAndLogSummary(ctx context.Context, refreshTime time.Time) {
-	total, err := h.repo.GetTotalCountries(ctx)
-	if err != nil {
-		h.logger.Error().Err(err).Msg("ImageGen: Failed to get total countries")
+// writeRefreshInProgress answers a refresh request made while another
+// refresh is running: with that refresh's run when joining, else with 409.
+func (h *ForexHandler) writeRefreshInProgress(w http.ResponseWriter, r *http.Request, inProgress *errs.RefreshInProgressError, join bool) {
+	if inProgress.RunID == 0 {
+		util.WriteJsonError(w, http.StatusConflict, "Refresh already in progress", nil)
 		return
 	}
 
-	top5, err := h.repo.GetTop5ByGDP(ctx)
+	location := fmt.Sprintf("/refresh/runs/%d", inProgress.RunID)
+	w.Header().Set("Location", location)
+
+	if join {
+		run, err := h.repo.GetRefreshRun(r.Context(), inProgress.RunID)
+		if err != nil {
+			h.logger.Error().Err(err).Msg("Failed to Fetch refresh run")
+			util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+			return
+		}
+		util.WriteJsonSuccess(w, http.StatusAccepted, run.ToResponse())
+		return
+	}
+
+	util.WriteJsonErrorWith(w, http.StatusConflict, "Refresh already in progress", nil, model.RefreshConflictResponse{
+		RunID: &inProgress.RunID,
+	})
+}
+
+func (h *ForexHandler) HandleGetRefreshJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("ImageGen: Failed to get top 5 countries by GDP")
+		util.WriteJsonError(w, http.StatusBadRequest, "Invalid job id", nil)
 		return
 	}
 
-	err = h.imgGen.GenerateSummary(total, top5, refreshTime)
+	job, err := h.repo.GetRefreshJob(r.Context(), id)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("ImageGen: Failed to generate summary image")
+		if errors.Is(err, errs.ErrNotFound) {
+			util.WriteJsonError(w, http.StatusNotFound, "Refresh job not found", nil)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to Fetch refresh job")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
 		return
 	}
 
-	h.logger.Info().Msg("Summary image generated successfully")
+	util.WriteJsonSuccess(w, http.StatusOK, job.ToResponse())
 }
 
 func (h *ForexHandler) HandleGetCountry(w http.ResponseWriter, r *http.Request) {
@@ -180,11 +142,26 @@ func (h *ForexHandler) HandleGetCountry(w http.ResponseWriter, r *http.Request)
 		filters.Currency = &currency
 	}
 
+	if v := r.URL.Query().Get("include_inactive"); v != "" {
+		includeInactive, err := strconv.ParseBool(v)
+		if err != nil {
+			details := "include_inactive must be true or false"
+			util.WriteJsonError(w, http.StatusBadRequest, "Invalid query parameter", &details)
+			return
+		}
+		filters.IncludeInactive = includeInactive
+	}
+
 	filters.SortKey = r.URL.Query().Get("sort")
 	if filters.SortKey == "" {
 		filters.SortKey = "name_asc"
 	}
 
+	base, rate, ok := h.baseParam(w, r)
+	if !ok {
+		return
+	}
+
 	countries, err := h.repo.GetCountries(r.Context(), filters)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to Fetch Countries")
@@ -196,18 +173,32 @@ func (h *ForexHandler) HandleGetCountry(w http.ResponseWriter, r *http.Request)
 		h.logger.Info().Msg("Database is empty")
 	}
 
-	util.WriteJsonSuccess(w, http.StatusOK, model.ToCountryResponses(countries))
+	responses := model.ToCountryResponses(countries)
+	for i := range responses {
+		responses[i].Rebase(base, rate)
+	}
+	util.WriteJsonSuccess(w, http.StatusOK, responses)
 
 }
 
+// HandleGetCountryByName looks a country up by its stored name, falling back
+// to ISO 3166-1 codes so /countries/gb works too.
 func (h *ForexHandler) HandleGetCountryByName(w http.ResponseWriter, r *http.Request) {
 	param := chi.URLParam(r, "name")
 
+	base, rate, ok := h.baseParam(w, r)
+	if !ok {
+		return
+	}
+
 	country, err := h.repo.GetCountryByName(r.Context(), param)
+	if errors.Is(err, errs.ErrNotFound) && model.CountryCodeKind(param) != "" {
+		country, err = h.repo.GetCountryByCode(r.Context(), param)
+	}
 
 	if err != nil {
 		if errors.Is(err, errs.ErrNotFound) {
-			util.WriteJsonError(w, http.StatusNotFound, "Country not found", nil)
+			h.writeCountryNotFound(w, r, param)
 			return
 		}
 		h.logger.Error().Err(err).Msg("Failed to Fetch Countries")
@@ -216,11 +207,44 @@ func (h *ForexHandler) HandleGetCountryByName(w http.ResponseWriter, r *http.Req
 	}
 
 	if country == nil {
-		util.WriteJsonError(w, http.StatusNotFound, "Country not found", nil)
+		h.writeCountryNotFound(w, r, param)
+		return
+	}
+
+	response := country.ToResponse()
+	response.Rebase(base, rate)
+	util.WriteJsonSuccess(w, http.StatusOK, response)
+}
+
+// HandleGetCountryByCode looks a country up by its ISO 3166-1 alpha-2,
+// alpha-3 or numeric code.
+func (h *ForexHandler) HandleGetCountryByCode(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	if model.CountryCodeKind(code) == "" {
+		details := "code must be an ISO 3166-1 alpha-2, alpha-3 or numeric code"
+		util.WriteJsonError(w, http.StatusBadRequest, "Invalid country code", &details)
+		return
+	}
+
+	base, rate, ok := h.baseParam(w, r)
+	if !ok {
 		return
 	}
 
-	util.WriteJsonSuccess(w, http.StatusOK, country.ToResponse())
+	country, err := h.repo.GetCountryByCode(r.Context(), code)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.writeCountryCodeNotFound(w, r, code)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to Fetch Countries")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	response := country.ToResponse()
+	response.Rebase(base, rate)
+	util.WriteJsonSuccess(w, http.StatusOK, response)
 }
 
 func (h *ForexHandler) HandleDeleteCountryByName(w http.ResponseWriter, r *http.Request) {
@@ -273,3 +297,34 @@ func (h *ForexHandler) HandleGetImage(w http.ResponseWriter, r *http.Request) {
 
 	http.ServeFile(w, r, imagePath)
 }
+
+func (h *ForexHandler) HandleGetDeletedCountries(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.repo.GetDeletedCountries(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to Fetch deleted countries")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	util.WriteJsonSuccess(w, http.StatusOK, model.ToDeletedCountryResponses(deleted))
+}
+
+// HandleRestoreCountry undoes a delete: the country comes back as it was
+// stored, and the next refresh downloads the country list in full to bring
+// it up to date.
+func (h *ForexHandler) HandleRestoreCountry(w http.ResponseWriter, r *http.Request) {
+	param := chi.URLParam(r, "name")
+
+	if err := h.repo.RestoreCountry(r.Context(), param); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			util.WriteJsonError(w, http.StatusNotFound, "Deleted country not found", nil)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to restore country")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+}
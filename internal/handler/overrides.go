@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/util"
+)
+
+const maxPatchBodyBytes = 1 << 20
+
+// HandlePatchCountry stores manual overrides for a country. They are applied
+// on every read and survive refreshes.
+func (h *ForexHandler) HandlePatchCountry(w http.ResponseWriter, r *http.Request) {
+	param := chi.URLParam(r, "name")
+
+	var req model.CountryPatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPatchBodyBytes)).Decode(&req); err != nil {
+		details := err.Error()
+		util.WriteJsonError(w, http.StatusBadRequest, "Invalid request body", &details)
+		return
+	}
+
+	req.Author = strings.TrimSpace(req.Author)
+	req.Reason = strings.TrimSpace(req.Reason)
+	switch {
+	case req.Author == "":
+		details := "author is required"
+		util.WriteJsonError(w, http.StatusBadRequest, "Validation failed", &details)
+		return
+	case req.Reason == "":
+		details := "reason is required"
+		util.WriteJsonError(w, http.StatusBadRequest, "Validation failed", &details)
+		return
+	case len(req.Fields) == 0:
+		details := "fields must name at least one of " + strings.Join(model.OverridableFields, ", ")
+		util.WriteJsonError(w, http.StatusBadRequest, "Validation failed", &details)
+		return
+	}
+
+	set := make(map[string]sql.NullString, len(req.Fields))
+	var remove, problems []string
+	for field, raw := range req.Fields {
+		value, drop, err := model.ParseOverrideValue(field, raw)
+		switch {
+		case err != nil:
+			problems = append(problems, err.Error())
+		case drop:
+			remove = append(remove, field)
+		default:
+			set[field] = value
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		details := strings.Join(problems, "; ")
+		util.WriteJsonError(w, http.StatusBadRequest, "Validation failed", &details)
+		return
+	}
+
+	// Reject a bad ?base= before anything is stored.
+	base, rate, ok := h.baseParam(w, r)
+	if !ok {
+		return
+	}
+
+	country, err := h.repo.GetCountryByName(r.Context(), param)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			util.WriteJsonError(w, http.StatusNotFound, "Country not found", nil)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to Fetch Countries")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	if err := h.repo.SetCountryOverrides(r.Context(), country.Name, req.Author, req.Reason, set, remove); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to save country overrides")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	country, err = h.repo.GetCountryByName(r.Context(), country.Name)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to Fetch Countries")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	response := country.ToResponse()
+	response.Rebase(base, rate)
+	util.WriteJsonSuccess(w, http.StatusOK, response)
+}
+
+func (h *ForexHandler) HandleGetCountryOverrides(w http.ResponseWriter, r *http.Request) {
+	param := chi.URLParam(r, "name")
+
+	country, err := h.repo.GetCountryByName(r.Context(), param)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			util.WriteJsonError(w, http.StatusNotFound, "Country not found", nil)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to Fetch Countries")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	overrides, err := h.repo.GetCountryOverrides(r.Context(), country.Name)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to Fetch country overrides")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	util.WriteJsonSuccess(w, http.StatusOK, model.ToCountryOverrideResponses(overrides))
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/util"
+)
+
+const defaultHistoryWindow = 30 * 24 * time.Hour
+
+func (h *ForexHandler) HandleGetRateHistory(w http.ResponseWriter, r *http.Request) {
+	code := strings.ToUpper(chi.URLParam(r, "code"))
+	query := r.URL.Query()
+
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		t, err := parseTimeParam(v, true)
+		if err != nil {
+			details := "to must be RFC 3339 or YYYY-MM-DD"
+			util.WriteJsonError(w, http.StatusBadRequest, "Invalid query parameter", &details)
+			return
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultHistoryWindow)
+	if v := query.Get("from"); v != "" {
+		t, err := parseTimeParam(v, false)
+		if err != nil {
+			details := "from must be RFC 3339 or YYYY-MM-DD"
+			util.WriteJsonError(w, http.StatusBadRequest, "Invalid query parameter", &details)
+			return
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		details := "from must not be after to"
+		util.WriteJsonError(w, http.StatusBadRequest, "Invalid query parameter", &details)
+		return
+	}
+
+	interval := query.Get("interval")
+	switch interval {
+	case "":
+		interval = model.IntervalRaw
+	case model.IntervalRaw, model.IntervalHour, model.IntervalDay, model.IntervalWeek, model.IntervalMonth:
+	default:
+		details := "interval must be one of raw, hour, day, week, month"
+		util.WriteJsonError(w, http.StatusBadRequest, "Invalid query parameter", &details)
+		return
+	}
+
+	history, err := h.repo.GetRateHistory(r.Context(), code, from, to)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to Fetch rate history")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	resp := model.RateHistoryResponse{
+		CurrencyCode: code,
+		From:         from,
+		To:           to,
+		Interval:     interval,
+		Points:       model.ToRateHistoryPoints(history, interval),
+	}
+	if len(history) > 0 {
+		resp.BaseCode = &history[0].BaseCode
+	}
+
+	util.WriteJsonSuccess(w, http.StatusOK, resp)
+}
+
+// parseTimeParam accepts RFC 3339 timestamps or plain dates. A plain date
+// used as an upper bound covers the whole day.
+func parseTimeParam(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, nil
+}
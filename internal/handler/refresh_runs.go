@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/util"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+func (h *ForexHandler) HandleListRefreshRuns(w http.ResponseWriter, r *http.Request) {
+	page, limit, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	runs, total, err := h.repo.ListRefreshRuns(r.Context(), limit, (page-1)*limit)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to Fetch refresh runs")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	util.WriteJsonSuccess(w, http.StatusOK, model.RefreshRunListResponse{
+		Runs:  model.ToRefreshRunResponses(runs),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+func (h *ForexHandler) HandleGetRefreshRun(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		util.WriteJsonError(w, http.StatusBadRequest, "Invalid run id", nil)
+		return
+	}
+
+	run, err := h.repo.GetRefreshRun(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			util.WriteJsonError(w, http.StatusNotFound, "Refresh run not found", nil)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to Fetch refresh run")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	util.WriteJsonSuccess(w, http.StatusOK, run.ToResponse())
+}
+
+// parsePagination reads the page (1-based) and limit query parameters. On
+// invalid input it writes a 400 and returns false.
+func parsePagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	query := r.URL.Query()
+
+	page := 1
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			details := "page must be a positive integer"
+			util.WriteJsonError(w, http.StatusBadRequest, "Invalid query parameter", &details)
+			return 0, 0, false
+		}
+		page = n
+	}
+
+	limit := defaultPageLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			details := "limit must be an integer between 1 and 100"
+			util.WriteJsonError(w, http.StatusBadRequest, "Invalid query parameter", &details)
+			return 0, 0, false
+		}
+		limit = n
+	}
+
+	return page, limit, true
+}
+
+func (h *ForexHandler) HandleGetRefreshRunDiff(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		util.WriteJsonError(w, http.StatusBadRequest, "Invalid run id", nil)
+		return
+	}
+
+	if _, err := h.repo.GetRefreshRun(r.Context(), id); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			util.WriteJsonError(w, http.StatusNotFound, "Refresh run not found", nil)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to Fetch refresh run")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	changes, err := h.repo.GetRefreshRunChanges(r.Context(), id)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to Fetch refresh run diff")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	util.WriteJsonSuccess(w, http.StatusOK, model.ToRefreshDiffResponse(id, changes))
+}
+
+func (h *ForexHandler) HandleGetRefreshRunQuarantine(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		util.WriteJsonError(w, http.StatusBadRequest, "Invalid run id", nil)
+		return
+	}
+
+	if _, err := h.repo.GetRefreshRun(r.Context(), id); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			util.WriteJsonError(w, http.StatusNotFound, "Refresh run not found", nil)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Failed to Fetch refresh run")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	quarantined, err := h.repo.GetQuarantinedCountries(r.Context(), id)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to Fetch quarantined countries")
+		util.WriteJsonError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+
+	util.WriteJsonSuccess(w, http.StatusOK, model.ToQuarantineListResponse(id, quarantined))
+}
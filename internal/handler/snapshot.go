@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HandleExport streams a snapshot of the stored dataset as NDJSON.
+func (h *ForexHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	filename := fmt.Sprintf("forex-snapshot-%s.ndjson", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if err := h.snapshots.Export(r.Context(), w); err != nil {
+		// Part of the snapshot may already be sent; without its trailer a
+		// restore will refuse it.
+		h.logger.Error().Err(err).Msg("Failed to export snapshot")
+	}
+}
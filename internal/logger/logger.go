@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"io"
 	"os"
 
 	"github.com/rs/zerolog"
@@ -8,9 +9,15 @@ import (
 )
 
 func NewLogger() zerolog.Logger {
+	return NewLoggerTo(os.Stdout)
+}
+
+// NewLoggerTo is NewLogger writing to out, for commands whose stdout carries
+// data.
+func NewLoggerTo(out io.Writer) zerolog.Logger {
 	zerolog.TimeFieldFormat = "2006-01-02 15:04:05"
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
-	consoleWriter := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02 15:04:05"}
+	consoleWriter := zerolog.ConsoleWriter{Out: out, TimeFormat: "2006-01-02 15:04:05"}
 
 	return zerolog.New(consoleWriter).
 		Level(zerolog.DebugLevel).
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Where a country alias came from. Manual aliases are never replaced by
+// upstream ones. A deleted alias keeps its row with AliasSourceDeleted so
+// upstream cannot bring it back; it is ignored until set again by hand.
+const (
+	AliasSourceUpstream = "upstream"
+	AliasSourceManual   = "manual"
+	AliasSourceDeleted  = "deleted"
+)
+
+// CountryAlias maps an alternative spelling, such as "usa" or "ivory coast",
+// to the stored name of a country.
+type CountryAlias struct {
+	Alias       string
+	CountryName string
+	Source      string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type CountryAliasResponse struct {
+	Alias     string    `json:"alias"`
+	Country   string    `json:"country"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CountryAliasRequest is the body of PUT /admin/aliases/{alias}. Country is
+// the name, alias or ISO code of the country the alias should point at.
+type CountryAliasRequest struct {
+	Country string `json:"country"`
+}
+
+// CountryNotFoundResponse holds the fields the 404 error body of a country
+// lookup carries: the stored names closest to what was asked for.
+type CountryNotFoundResponse struct {
+	Suggestions []string `json:"suggestions"`
+}
+
+func (a *CountryAlias) ToResponse() CountryAliasResponse {
+	return CountryAliasResponse{
+		Alias:     a.Alias,
+		Country:   a.CountryName,
+		Source:    a.Source,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+	}
+}
+
+func ToCountryAliasResponses(aliases []CountryAlias) []CountryAliasResponse {
+	responses := make([]CountryAliasResponse, len(aliases))
+	for i := range aliases {
+		responses[i] = aliases[i].ToResponse()
+	}
+	return responses
+}
+
+// NormalizeCountryName folds a country name or alias to the form it is
+// stored and looked up in: lower case, single spaces, plain apostrophes.
+// Accents are left alone; the database collation ignores them.
+func NormalizeCountryName(name string) string {
+	name = strings.ReplaceAll(name, "’", "'")
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -2,13 +2,25 @@ package model
 
 import (
 	"database/sql"
+	"math"
 	"time"
 )
 
 type CountryCurrency struct {
-	Code string `json:"code"`
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+}
+
+// CountryLanguage is a language spoken in a country. Code is the ISO 639-2
+// (or 639-3) code where the source provides one.
+type CountryLanguage struct {
+	Code string `json:"iso639_2"`
+	Name string `json:"name"`
 }
 
+// Country is a country as received from a country source. JSON tags follow
+// the restcountries v2 schema; other sources are converted to it.
 type Country struct {
 	Name       string            `json:"name"`
 	Capital    string            `json:"capital"`
@@ -16,12 +28,101 @@ type Country struct {
 	Population int64             `json:"population"`
 	FlagURL    string            `json:"flag"`
 	Currencies []CountryCurrency `json:"currencies"`
+
+	// The fields below are only set when the source provides them.
+	OfficialName string            `json:"officialName,omitempty"`
+	Alpha2Code   string            `json:"alpha2Code,omitempty"`
+	Alpha3Code   string            `json:"alpha3Code,omitempty"`
+	NumericCode  string            `json:"numericCode,omitempty"`
+	Subregion    string            `json:"subregion,omitempty"`
+	Area         float64           `json:"area,omitempty"`
+	Languages    []CountryLanguage `json:"languages,omitempty"`
+	Timezones    []string          `json:"timezones,omitempty"`
+	CallingCodes []string          `json:"callingCodes,omitempty"`
+	Borders      []string          `json:"borders,omitempty"`
+	AltSpellings []string          `json:"altSpellings,omitempty"`
 }
 
 type ExchangeRates struct {
-	Rates map[string]float64 `json:"rates"`
+	BaseCode           string             `json:"base_code"`
+	TimeLastUpdateUnix int64              `json:"time_last_update_unix"`
+	Rates              map[string]float64 `json:"rates"`
+
+	// Source names the provider that supplied Rates. Sources overrides it
+	// for individual currencies filled in from another provider.
+	Source  string            `json:"-"`
+	Sources map[string]string `json:"-"`
+	// Provenance describes each provider named by Source and Sources. It
+	// is empty for rates loaded back from the database.
+	Provenance map[string]Provenance `json:"-"`
 }
 
+// SourceOf returns the provider that supplied the rate for code.
+func (e *ExchangeRates) SourceOf(code string) string {
+	if source, ok := e.Sources[code]; ok {
+		return source
+	}
+	return e.Source
+}
+
+// ProvenanceOf returns the provenance of the rate for code, if known.
+func (e *ExchangeRates) ProvenanceOf(code string) (Provenance, bool) {
+	p, ok := e.Provenance[e.SourceOf(code)]
+	return p, ok
+}
+
+const (
+	CountryStatusActive   = "active"
+	CountryStatusInactive = "inactive"
+)
+
+// GDPMethodDataset marks estimates taken from a table of published GDP
+// figures. Unlike the other methods they do not depend on population or the
+// exchange rate.
+const GDPMethodDataset = "dataset"
+
+// Kinds of ISO 3166-1 country code.
+const (
+	CountryCodeAlpha2  = "alpha2"
+	CountryCodeAlpha3  = "alpha3"
+	CountryCodeNumeric = "numeric"
+)
+
+// CountryCodeKind reports which kind of ISO 3166-1 code code is shaped
+// like, or "" if it is not shaped like one.
+func CountryCodeKind(code string) string {
+	digits, letters := 0, 0
+	for _, r := range code {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			letters++
+		default:
+			return ""
+		}
+	}
+
+	switch {
+	case letters == 2 && digits == 0:
+		return CountryCodeAlpha2
+	case letters == 3 && digits == 0:
+		return CountryCodeAlpha3
+	case digits == 3 && letters == 0:
+		return CountryCodeNumeric
+	default:
+		return ""
+	}
+}
+
+// Reconcile modes for stored countries that are missing from the latest
+// country fetch.
+const (
+	ReconcileKeep     = "keep"
+	ReconcileInactive = "inactive"
+	ReconcileDelete   = "delete"
+)
+
 type CountryDBRow struct {
 	ID              int64
 	Name            string
@@ -31,27 +132,171 @@ type CountryDBRow struct {
 	CurrencyCode    sql.NullString
 	ExchangeRate    sql.NullFloat64
 	EstimatedGDP    sql.NullFloat64
+	GDPMethod       sql.NullString
 	FlagURL         sql.NullString
 	LastRefreshedAt sql.NullTime // Use sql.NullTime
+	RateSource      sql.NullString
+	// RatesStale is set when the last refresh could not fetch rates and the
+	// previously stored ones were kept.
+	RatesStale    bool
+	Status        string
+	InactiveSince sql.NullTime
+	// Provenance of the country record and of its primary exchange rate.
+	// RatesUpdatedAt is the rate provider's own timestamp; the fetch times
+	// are when the data was downloaded, not when it was last stored.
+	CountrySource      sql.NullString
+	CountrySourceURL   sql.NullString
+	CountriesFetchedAt sql.NullTime
+	RateSourceURL      sql.NullString
+	RatesUpdatedAt     sql.NullTime
+	RatesFetchedAt     sql.NullTime
+	Alpha2Code         sql.NullString
+	Alpha3Code         sql.NullString
+	NumericCode        sql.NullString
+	Subregion          sql.NullString
+	Area               sql.NullFloat64
+	Currencies         []CountryCurrencyDBRow
+	// The lists below are stored in child tables, in upstream order.
+	Languages    []CountryLanguage
+	Timezones    []string
+	CallingCodes []string
+	Borders      []string
+	// AltSpellings are seeded into country_aliases on refresh. They are not
+	// read back with the row.
+	AltSpellings []string
+	// OverriddenFields lists the fields replaced by manual overrides.
+	OverriddenFields []string
+}
+
+// CountryCurrencyDBRow is one legal tender of a country. CurrencyCode on
+// CountryDBRow mirrors the entry flagged IsPrimary.
+type CountryCurrencyDBRow struct {
+	Code         string
+	Name         sql.NullString
+	Symbol       sql.NullString
+	IsPrimary    bool
+	ExchangeRate sql.NullFloat64
+	RateSource   sql.NullString
+}
+
+type CurrencyResponse struct {
+	Code         string   `json:"code"`
+	Name         *string  `json:"name"`
+	Symbol       *string  `json:"symbol"`
+	IsPrimary    bool     `json:"is_primary"`
+	ExchangeRate *float64 `json:"exchange_rate"`
+	RateSource   *string  `json:"rate_source"`
+}
+
+func (c *CountryCurrencyDBRow) ToResponse() CurrencyResponse {
+	var name, symbol, rateSource *string
+	var exchangeRate *float64
+
+	if c.Name.Valid {
+		name = &c.Name.String
+	}
+	if c.Symbol.Valid {
+		symbol = &c.Symbol.String
+	}
+	if c.ExchangeRate.Valid {
+		exchangeRate = &c.ExchangeRate.Float64
+	}
+	if c.RateSource.Valid {
+		rateSource = &c.RateSource.String
+	}
+
+	return CurrencyResponse{
+		Code:         c.Code,
+		Name:         name,
+		Symbol:       symbol,
+		IsPrimary:    c.IsPrimary,
+		ExchangeRate: exchangeRate,
+		RateSource:   rateSource,
+	}
+}
+
+type LanguageResponse struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
 }
 
 type CountryResponse struct {
-	ID              int64      `json:"id"`
-	Name            string     `json:"name"`
-	Capital         *string    `json:"capital"`
-	Region          *string    `json:"region"`
-	Population      int64      `json:"population"`
-	CurrencyCode    *string    `json:"currency_code"`
-	ExchangeRate    *float64   `json:"exchange_rate"`
-	EstimatedGDP    *float64   `json:"estimated_gdp"`
-	FlagURL         *string    `json:"flag_url"`
-	LastRefreshedAt *time.Time `json:"last_refreshed_at"`
+	ID               int64              `json:"id"`
+	Name             string             `json:"name"`
+	Alpha2Code       *string            `json:"alpha2_code"`
+	Alpha3Code       *string            `json:"alpha3_code"`
+	NumericCode      *string            `json:"numeric_code"`
+	Capital          *string            `json:"capital"`
+	Region           *string            `json:"region"`
+	Subregion        *string            `json:"subregion"`
+	Area             *float64           `json:"area"`
+	Languages        []LanguageResponse `json:"languages"`
+	Timezones        []string           `json:"timezones"`
+	CallingCodes     []string           `json:"calling_codes"`
+	Borders          []string           `json:"borders"`
+	Population       int64              `json:"population"`
+	CurrencyCode     *string            `json:"currency_code"`
+	ExchangeRate     *float64           `json:"exchange_rate"`
+	RateSource       *string            `json:"rate_source"`
+	RatesStale       bool               `json:"rates_stale"`
+	EstimatedGDP     *float64           `json:"estimated_gdp"`
+	GDPMethod        *string            `json:"gdp_method"`
+	FlagURL          *string            `json:"flag_url"`
+	LastRefreshedAt  *time.Time         `json:"last_refreshed_at"`
+	Status           string             `json:"status"`
+	InactiveSince    *time.Time         `json:"inactive_since"`
+	OverriddenFields []string           `json:"overridden_fields"`
+	Currencies       []CurrencyResponse `json:"currencies"`
+
+	CountrySource      *string    `json:"country_source"`
+	CountrySourceURL   *string    `json:"country_source_url"`
+	CountriesFetchedAt *time.Time `json:"countries_fetched_at"`
+	RateSourceURL      *string    `json:"rate_source_url"`
+	RatesUpdatedAt     *time.Time `json:"rates_updated_at"`
+	RatesFetchedAt     *time.Time `json:"rates_fetched_at"`
+	// BaseCurrency is the currency exchange rates are quoted against and
+	// estimated GDP is expressed in.
+	BaseCurrency string `json:"base_currency"`
+}
+
+// Rebase re-expresses rates and GDP in base. rate is the value of one unit
+// of the current base in the new one. Results keep the precision they are
+// stored with.
+func (c *CountryResponse) Rebase(base string, rate float64) {
+	c.BaseCurrency = base
+	if rate == 1 {
+		return
+	}
+
+	if c.ExchangeRate != nil {
+		converted := roundTo(*c.ExchangeRate/rate, 6)
+		c.ExchangeRate = &converted
+	}
+	if c.EstimatedGDP != nil {
+		converted := roundTo(*c.EstimatedGDP*rate, 2)
+		c.EstimatedGDP = &converted
+	}
+	for i := range c.Currencies {
+		if c.Currencies[i].ExchangeRate != nil {
+			converted := roundTo(*c.Currencies[i].ExchangeRate/rate, 6)
+			c.Currencies[i].ExchangeRate = &converted
+		}
+	}
+}
+
+func roundTo(v float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
 }
 
 func (db *CountryDBRow) ToResponse() CountryResponse {
-	var capital, region, currencyCode, flagURL *string
+	var capital, region, currencyCode, flagURL, rateSource, gdpMethod *string
+	var countrySource, countrySourceURL, rateSourceURL *string
 	var exchangeRate, estimatedGDP *float64
-	var lastRefreshed *time.Time
+	var lastRefreshed, inactiveSince *time.Time
+	var countriesFetched, ratesUpdated, ratesFetched *time.Time
+	var alpha2Code, alpha3Code, numericCode, subregion *string
+	var area *float64
 
 	if db.Capital.Valid {
 		capital = &db.Capital.String
@@ -68,27 +313,112 @@ func (db *CountryDBRow) ToResponse() CountryResponse {
 	if db.ExchangeRate.Valid {
 		exchangeRate = &db.ExchangeRate.Float64
 	}
+	if db.RateSource.Valid {
+		rateSource = &db.RateSource.String
+	}
 	if db.EstimatedGDP.Valid {
 		estimatedGDP = &db.EstimatedGDP.Float64
 	}
+	if db.GDPMethod.Valid {
+		gdpMethod = &db.GDPMethod.String
+	}
 	if db.LastRefreshedAt.Valid {
 		lastRefreshed = &db.LastRefreshedAt.Time
 	}
+	if db.InactiveSince.Valid {
+		inactiveSince = &db.InactiveSince.Time
+	}
+	if db.CountrySource.Valid {
+		countrySource = &db.CountrySource.String
+	}
+	if db.CountrySourceURL.Valid {
+		countrySourceURL = &db.CountrySourceURL.String
+	}
+	if db.CountriesFetchedAt.Valid {
+		countriesFetched = &db.CountriesFetchedAt.Time
+	}
+	if db.RateSourceURL.Valid {
+		rateSourceURL = &db.RateSourceURL.String
+	}
+	if db.RatesUpdatedAt.Valid {
+		ratesUpdated = &db.RatesUpdatedAt.Time
+	}
+	if db.RatesFetchedAt.Valid {
+		ratesFetched = &db.RatesFetchedAt.Time
+	}
+	if db.Alpha2Code.Valid {
+		alpha2Code = &db.Alpha2Code.String
+	}
+	if db.Alpha3Code.Valid {
+		alpha3Code = &db.Alpha3Code.String
+	}
+	if db.NumericCode.Valid {
+		numericCode = &db.NumericCode.String
+	}
+	if db.Subregion.Valid {
+		subregion = &db.Subregion.String
+	}
+	if db.Area.Valid {
+		area = &db.Area.Float64
+	}
+
+	languages := make([]LanguageResponse, len(db.Languages))
+	for i, language := range db.Languages {
+		languages[i] = LanguageResponse{Code: language.Code, Name: language.Name}
+	}
+
+	overridden := nonNil(db.OverriddenFields)
+
+	currencies := make([]CurrencyResponse, len(db.Currencies))
+	for i, currency := range db.Currencies {
+		currencies[i] = currency.ToResponse()
+	}
 
 	return CountryResponse{
-		ID:              db.ID,
-		Name:            db.Name,
-		Population:      db.Population,
-		Capital:         capital,
-		Region:          region,
-		CurrencyCode:    currencyCode,
-		ExchangeRate:    exchangeRate,
-		EstimatedGDP:    estimatedGDP,
-		FlagURL:         flagURL,
-		LastRefreshedAt: lastRefreshed,
+		ID:               db.ID,
+		Name:             db.Name,
+		Alpha2Code:       alpha2Code,
+		Alpha3Code:       alpha3Code,
+		NumericCode:      numericCode,
+		Population:       db.Population,
+		Capital:          capital,
+		Region:           region,
+		Subregion:        subregion,
+		Area:             area,
+		Languages:        languages,
+		Timezones:        nonNil(db.Timezones),
+		CallingCodes:     nonNil(db.CallingCodes),
+		Borders:          nonNil(db.Borders),
+		CurrencyCode:     currencyCode,
+		ExchangeRate:     exchangeRate,
+		RateSource:       rateSource,
+		RatesStale:       db.RatesStale,
+		EstimatedGDP:     estimatedGDP,
+		GDPMethod:        gdpMethod,
+		FlagURL:          flagURL,
+		LastRefreshedAt:  lastRefreshed,
+		Status:           db.Status,
+		InactiveSince:    inactiveSince,
+		OverriddenFields: overridden,
+		Currencies:       currencies,
+
+		CountrySource:      countrySource,
+		CountrySourceURL:   countrySourceURL,
+		CountriesFetchedAt: countriesFetched,
+		RateSourceURL:      rateSourceURL,
+		RatesUpdatedAt:     ratesUpdated,
+		RatesFetchedAt:     ratesFetched,
 	}
 }
 
+// nonNil returns values, or an empty slice so it encodes as [] not null.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
 // Convert slice
 func ToCountryResponses(dbCountries []CountryDBRow) []CountryResponse {
 	responses := make([]CountryResponse, len(dbCountries))
@@ -99,29 +429,79 @@ func ToCountryResponses(dbCountries []CountryDBRow) []CountryResponse {
 }
 
 type CountryFilters struct {
-	Region   *string
-	Currency *string
-	SortKey  string
+	Region          *string
+	Currency        *string
+	SortKey         string
+	IncludeInactive bool
+	// Raw skips manual overrides and returns upstream values as stored.
+	Raw bool
 }
 
 type Stats struct {
-	TotalCountries  int          `db:"total_countries"`
-	LastRefreshedAt sql.NullTime `db:"last_refreshed_at"`
+	TotalCountries       int          `db:"total_countries"`
+	LastRefreshedAt      sql.NullTime `db:"last_refreshed_at"`
+	CountriesRefreshedAt sql.NullTime `db:"countries_refreshed_at"`
+	RatesRefreshedAt     sql.NullTime `db:"rates_refreshed_at"`
+	RatesStale           bool         `db:"rates_stale"`
+	BaseCurrency         string       `db:"base_currency"`
+	Sources              []UpstreamSource
 }
 type StatsResponse struct {
-	TotalCountries  int        `json:"total_countries"`
-	LastRefreshedAt *time.Time `json:"last_refreshed_at"`
+	TotalCountries       int        `json:"total_countries"`
+	LastRefreshedAt      *time.Time `json:"last_refreshed_at"`
+	CountriesRefreshedAt *time.Time `json:"countries_refreshed_at"`
+	RatesRefreshedAt     *time.Time `json:"rates_refreshed_at"`
+	RatesStale           bool       `json:"rates_stale"`
+	BaseCurrency         string     `json:"base_currency"`
+	// Sources reports how fresh each provider's data was when last fetched,
+	// which can lag the refresh times above.
+	Sources []UpstreamSourceResponse `json:"sources"`
 }
 
 func (s *Stats) ToResponse() StatsResponse {
-	var lastRefresh *time.Time
+	var lastRefresh, countriesRefresh, ratesRefresh *time.Time
+
+	sources := make([]UpstreamSourceResponse, len(s.Sources))
+	for i, source := range s.Sources {
+		sources[i] = source.ToResponse()
+	}
 
 	if s.LastRefreshedAt.Valid {
 		lastRefresh = &s.LastRefreshedAt.Time
 	}
+	if s.CountriesRefreshedAt.Valid {
+		countriesRefresh = &s.CountriesRefreshedAt.Time
+	}
+	if s.RatesRefreshedAt.Valid {
+		ratesRefresh = &s.RatesRefreshedAt.Time
+	}
 
 	return StatsResponse{
-		TotalCountries:  s.TotalCountries,
-		LastRefreshedAt: lastRefresh,
+		TotalCountries:       s.TotalCountries,
+		LastRefreshedAt:      lastRefresh,
+		CountriesRefreshedAt: countriesRefresh,
+		RatesRefreshedAt:     ratesRefresh,
+		RatesStale:           s.RatesStale,
+		BaseCurrency:         s.BaseCurrency,
+		Sources:              sources,
+	}
+}
+
+// DeletedCountry is a tombstone left by DELETE /countries/{name}.
+type DeletedCountry struct {
+	Name      string
+	DeletedAt time.Time
+}
+
+type DeletedCountryResponse struct {
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+func ToDeletedCountryResponses(deleted []DeletedCountry) []DeletedCountryResponse {
+	responses := make([]DeletedCountryResponse, len(deleted))
+	for i, d := range deleted {
+		responses[i] = DeletedCountryResponse{Name: d.Name, DeletedAt: d.DeletedAt}
 	}
+	return responses
 }
@@ -0,0 +1,80 @@
+package model
+
+import "testing"
+
+func TestCountryResponseRebase(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name         string
+		rate         float64
+		exchangeRate *float64
+		gdp          *float64
+		currencyRate *float64
+		wantRate     *float64
+		wantGDP      *float64
+		wantCurrency *float64
+	}{
+		{
+			name:         "rates divide and GDP multiplies",
+			rate:         0.8,
+			exchangeRate: float(1600),
+			gdp:          float(1000),
+			currencyRate: float(1600),
+			wantRate:     float(2000),
+			wantGDP:      float(800),
+			wantCurrency: float(2000),
+		},
+		{
+			name:         "rounded to stored precision",
+			rate:         3,
+			exchangeRate: float(1),
+			gdp:          float(0.005),
+			wantRate:     float(0.333333),
+			wantGDP:      float(0.02),
+		},
+		{
+			name:         "rate of one leaves figures alone",
+			rate:         1,
+			exchangeRate: float(1.23456789),
+			gdp:          float(10.555),
+			wantRate:     float(1.23456789),
+			wantGDP:      float(10.555),
+		},
+		{
+			name: "missing figures stay missing",
+			rate: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := CountryResponse{ExchangeRate: tt.exchangeRate, EstimatedGDP: tt.gdp, BaseCurrency: "USD"}
+			if tt.currencyRate != nil {
+				c.Currencies = []CurrencyResponse{{ExchangeRate: tt.currencyRate}}
+			}
+
+			c.Rebase("EUR", tt.rate)
+
+			if c.BaseCurrency != "EUR" {
+				t.Errorf("BaseCurrency = %q, want EUR", c.BaseCurrency)
+			}
+			assertFloat(t, "ExchangeRate", c.ExchangeRate, tt.wantRate)
+			assertFloat(t, "EstimatedGDP", c.EstimatedGDP, tt.wantGDP)
+			if tt.currencyRate != nil {
+				assertFloat(t, "Currencies[0].ExchangeRate", c.Currencies[0].ExchangeRate, tt.wantCurrency)
+			}
+		})
+	}
+}
+
+func assertFloat(t *testing.T, field string, got, want *float64) {
+	t.Helper()
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil:
+		t.Errorf("%s = %v, want %v", field, got, want)
+	case *got != *want:
+		t.Errorf("%s = %v, want %v", field, *got, *want)
+	}
+}
@@ -0,0 +1,179 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OverridableFields are the country columns that can be overridden by hand.
+// Derived values such as exchange_rate and estimated_gdp are not.
+var OverridableFields = []string{"capital", "region", "population", "currency_code", "flag_url"}
+
+// CountryOverride replaces one upstream field of a country on the read path.
+// An invalid Value overrides the field to null.
+type CountryOverride struct {
+	CountryName string
+	Field       string
+	Value       sql.NullString
+	Author      string
+	Reason      string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type CountryOverrideResponse struct {
+	Field     string    `json:"field"`
+	Value     *string   `json:"value"`
+	Author    string    `json:"author"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func ToCountryOverrideResponses(overrides []CountryOverride) []CountryOverrideResponse {
+	responses := make([]CountryOverrideResponse, len(overrides))
+	for i, o := range overrides {
+		responses[i] = CountryOverrideResponse{
+			Field:     o.Field,
+			Value:     nullString(o.Value),
+			Author:    o.Author,
+			Reason:    o.Reason,
+			CreatedAt: o.CreatedAt,
+			UpdatedAt: o.UpdatedAt,
+		}
+	}
+	return responses
+}
+
+// CountryPatchRequest is the body of PATCH /countries/{name}. A null field
+// value removes that field's override.
+type CountryPatchRequest struct {
+	Author string                     `json:"author"`
+	Reason string                     `json:"reason"`
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+// ParseOverrideValue validates a PATCH value for field. remove is true when
+// the value is null, meaning the override should be dropped.
+func ParseOverrideValue(field string, raw json.RawMessage) (value sql.NullString, remove bool, err error) {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return sql.NullString{}, true, nil
+	}
+
+	switch field {
+	case "population":
+		var n int64
+		if err := json.Unmarshal(raw, &n); err != nil || n < 0 {
+			return sql.NullString{}, false, errors.New("population must be a non-negative integer")
+		}
+		return sql.NullString{String: strconv.FormatInt(n, 10), Valid: true}, false, nil
+	case "capital", "region", "currency_code", "flag_url":
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return sql.NullString{}, false, fmt.Errorf("%s must be a string or null", field)
+		}
+		s = strings.TrimSpace(s)
+		switch field {
+		case "capital", "region":
+			s = strings.ToLower(s)
+		case "currency_code":
+			s = strings.ToUpper(s)
+		}
+		return sql.NullString{String: s, Valid: s != ""}, false, nil
+	default:
+		return sql.NullString{}, false, fmt.Errorf("%s cannot be overridden", field)
+	}
+}
+
+// ApplyOverrides replaces upstream values with the given overrides and
+// records which fields were overridden. Overriding currency_code also moves
+// the primary flag and exchange rate to that currency when the country uses
+// it; otherwise the code replaces the primary currency, priced from rates,
+// the stored rate of each overridden code. The GDP estimate follows the
+// overridden population and rate.
+func (c *CountryDBRow) ApplyOverrides(overrides []CountryOverride, rates map[string]float64) {
+	population, rate := c.Population, c.ExchangeRate
+	defer c.rescaleGDP(population, rate)
+
+	for _, o := range overrides {
+		switch o.Field {
+		case "capital":
+			c.Capital = o.Value
+		case "region":
+			c.Region = o.Value
+		case "flag_url":
+			c.FlagURL = o.Value
+		case "population":
+			n, err := strconv.ParseInt(o.Value.String, 10, 64)
+			if !o.Value.Valid || err != nil {
+				continue
+			}
+			c.Population = n
+		case "currency_code":
+			c.overrideCurrency(o.Value, rates)
+		default:
+			continue
+		}
+		c.OverriddenFields = append(c.OverriddenFields, o.Field)
+	}
+}
+
+// overrideCurrency makes code the primary currency. See ApplyOverrides.
+func (c *CountryDBRow) overrideCurrency(code sql.NullString, rates map[string]float64) {
+	c.CurrencyCode = code
+	c.ExchangeRate = sql.NullFloat64{}
+	c.RateSource = sql.NullString{}
+
+	listed, primary := false, 0
+	for i := range c.Currencies {
+		currency := &c.Currencies[i]
+		if currency.IsPrimary {
+			primary = i
+		}
+		currency.IsPrimary = code.Valid && currency.Code == code.String
+		if currency.IsPrimary {
+			listed = true
+			c.ExchangeRate = currency.ExchangeRate
+			c.RateSource = currency.RateSource
+		}
+	}
+	if listed || !code.Valid {
+		return
+	}
+
+	if rate, ok := rates[code.String]; ok && rate > 0 {
+		c.ExchangeRate = sql.NullFloat64{Float64: rate, Valid: true}
+	}
+	replacement := CountryCurrencyDBRow{Code: code.String, IsPrimary: true, ExchangeRate: c.ExchangeRate}
+	if len(c.Currencies) == 0 {
+		c.Currencies = []CountryCurrencyDBRow{replacement}
+		return
+	}
+	// The upstream primary is the one the override corrects.
+	c.Currencies[primary] = replacement
+}
+
+// rescaleGDP brings the GDP estimate, made from population and rate, in line
+// with the row's current population and exchange rate. Estimates other than
+// dataset ones are proportional to population over the rate; they are
+// cleared when that cannot be worked out.
+func (c *CountryDBRow) rescaleGDP(population int64, rate sql.NullFloat64) {
+	if !c.EstimatedGDP.Valid || c.GDPMethod.String == GDPMethodDataset {
+		return
+	}
+	if population == c.Population && rate == c.ExchangeRate {
+		return
+	}
+	if population <= 0 || !rate.Valid || rate.Float64 == 0 || !c.ExchangeRate.Valid || c.ExchangeRate.Float64 == 0 {
+		c.EstimatedGDP = sql.NullFloat64{}
+		c.GDPMethod = sql.NullString{}
+		return
+	}
+	c.EstimatedGDP.Float64 *= float64(c.Population) / float64(population) * rate.Float64 / c.ExchangeRate.Float64
+}
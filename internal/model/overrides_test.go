@@ -0,0 +1,81 @@
+package model
+
+import (
+	"database/sql"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestApplyOverridesRescalesGDP(t *testing.T) {
+	row := func(method string) CountryDBRow {
+		return CountryDBRow{
+			Name:         "a",
+			Population:   1000,
+			CurrencyCode: sql.NullString{String: "AAA", Valid: true},
+			ExchangeRate: sql.NullFloat64{Float64: 2, Valid: true},
+			EstimatedGDP: sql.NullFloat64{Float64: 500, Valid: true},
+			GDPMethod:    sql.NullString{String: method, Valid: true},
+			Currencies: []CountryCurrencyDBRow{
+				{Code: "AAA", IsPrimary: true, ExchangeRate: sql.NullFloat64{Float64: 2, Valid: true}},
+				{Code: "BBB", ExchangeRate: sql.NullFloat64{Float64: 4, Valid: true}},
+			},
+		}
+	}
+	override := func(field, value string) CountryOverride {
+		return CountryOverride{Field: field, Value: sql.NullString{String: value, Valid: true}}
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		overrides []CountryOverride
+		rates     map[string]float64
+		want      sql.NullFloat64
+	}{
+		{"population scales the estimate", "formula", []CountryOverride{override("population", "3000")}, nil, sql.NullFloat64{Float64: 1500, Valid: true}},
+		{"currency scales by the new rate", "random", []CountryOverride{override("currency_code", "BBB")}, nil, sql.NullFloat64{Float64: 250, Valid: true}},
+		{"both together", "formula", []CountryOverride{override("population", "2000"), override("currency_code", "BBB")}, nil, sql.NullFloat64{Float64: 500, Valid: true}},
+		{"unknown currency clears the estimate", "formula", []CountryOverride{override("currency_code", "ZZZ")}, nil, sql.NullFloat64{}},
+		{"unlisted currency is priced from stored rates", "formula", []CountryOverride{override("currency_code", "ZZZ")}, map[string]float64{"ZZZ": 8}, sql.NullFloat64{Float64: 125, Valid: true}},
+		{"dataset estimates are left alone", GDPMethodDataset, []CountryOverride{override("population", "3000")}, nil, sql.NullFloat64{Float64: 500, Valid: true}},
+		{"unrelated fields are left alone", "formula", []CountryOverride{override("capital", "x")}, nil, sql.NullFloat64{Float64: 500, Valid: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := row(tt.method)
+			c.ApplyOverrides(tt.overrides, tt.rates)
+			if c.EstimatedGDP.Valid != tt.want.Valid || math.Abs(c.EstimatedGDP.Float64-tt.want.Float64) > 1e-9 {
+				t.Errorf("EstimatedGDP = %+v, want %+v", c.EstimatedGDP, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOverridesReplacesUnlistedPrimary(t *testing.T) {
+	c := CountryDBRow{
+		Name:         "sierra leone",
+		CurrencyCode: sql.NullString{String: "SLL", Valid: true},
+		ExchangeRate: sql.NullFloat64{Float64: 20000, Valid: true},
+		Currencies: []CountryCurrencyDBRow{
+			{Code: "SLL", IsPrimary: true, ExchangeRate: sql.NullFloat64{Float64: 20000, Valid: true}},
+			{Code: "USD", ExchangeRate: sql.NullFloat64{Float64: 1, Valid: true}},
+		},
+	}
+
+	c.ApplyOverrides([]CountryOverride{
+		{Field: "currency_code", Value: sql.NullString{String: "SLE", Valid: true}},
+	}, map[string]float64{"SLE": 20})
+
+	if c.ExchangeRate != (sql.NullFloat64{Float64: 20, Valid: true}) {
+		t.Errorf("ExchangeRate = %+v, want 20", c.ExchangeRate)
+	}
+	want := []CountryCurrencyDBRow{
+		{Code: "SLE", IsPrimary: true, ExchangeRate: sql.NullFloat64{Float64: 20, Valid: true}},
+		{Code: "USD", ExchangeRate: sql.NullFloat64{Float64: 1, Valid: true}},
+	}
+	if !reflect.DeepEqual(c.Currencies, want) {
+		t.Errorf("Currencies = %+v, want %+v", c.Currencies, want)
+	}
+}
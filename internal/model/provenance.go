@@ -0,0 +1,71 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Kinds of upstream source.
+const (
+	SourceKindCountries = "countries"
+	SourceKindRates     = "rates"
+)
+
+// Provenance records where a provider's data came from. UpdatedAt is the
+// upstream's own timestamp for the data and is zero when it gives none;
+// FetchedAt is when the data was downloaded.
+type Provenance struct {
+	Source    string
+	URL       string
+	UpdatedAt time.Time
+	FetchedAt time.Time
+}
+
+// UpstreamSource is the provenance of the latest data fetched from one
+// provider, as reported by /status.
+type UpstreamSource struct {
+	Name          string
+	Kind          string
+	URL           sql.NullString
+	DataUpdatedAt sql.NullTime
+	FetchedAt     time.Time
+}
+
+type UpstreamSourceResponse struct {
+	Name          string     `json:"name"`
+	Kind          string     `json:"kind"`
+	URL           *string    `json:"url"`
+	DataUpdatedAt *time.Time `json:"data_updated_at"`
+	FetchedAt     time.Time  `json:"fetched_at"`
+}
+
+// NewUpstreamSource stores p as the latest fetch from a provider of kind.
+func NewUpstreamSource(kind string, p Provenance) UpstreamSource {
+	return UpstreamSource{
+		Name:          p.Source,
+		Kind:          kind,
+		URL:           sql.NullString{String: p.URL, Valid: p.URL != ""},
+		DataUpdatedAt: sql.NullTime{Time: p.UpdatedAt, Valid: !p.UpdatedAt.IsZero()},
+		FetchedAt:     p.FetchedAt,
+	}
+}
+
+func (u *UpstreamSource) ToResponse() UpstreamSourceResponse {
+	var url *string
+	var dataUpdatedAt *time.Time
+
+	if u.URL.Valid {
+		url = &u.URL.String
+	}
+	if u.DataUpdatedAt.Valid {
+		dataUpdatedAt = &u.DataUpdatedAt.Time
+	}
+
+	return UpstreamSourceResponse{
+		Name:          u.Name,
+		Kind:          u.Kind,
+		URL:           url,
+		DataUpdatedAt: dataUpdatedAt,
+		FetchedAt:     u.FetchedAt,
+	}
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Validation rules applied to upstream records before they are stored.
+const (
+	RuleName         = "name"
+	RulePopulation   = "population"
+	RuleCurrencyCode = "currency_code"
+	RuleRate         = "rate"
+)
+
+// QuarantinedCountry is an upstream country record a refresh refused to
+// store. Rule is the first rule it failed; Reason lists every failure.
+// Record holds the record as received, in JSON. When Currency is set only
+// that currency was dropped and the country itself was stored.
+type QuarantinedCountry struct {
+	ID            int64
+	RunID         int64
+	CountryName   string
+	Currency      sql.NullString
+	Rule          string
+	Reason        string
+	Record        sql.NullString
+	QuarantinedAt time.Time
+}
+
+// WholeCountry reports whether the country itself was kept out, rather
+// than just one of its currencies.
+func (q *QuarantinedCountry) WholeCountry() bool {
+	return !q.Currency.Valid
+}
+
+type QuarantinedCountryResponse struct {
+	Country       string          `json:"country"`
+	Currency      string          `json:"currency,omitempty"`
+	Rule          string          `json:"rule"`
+	Reason        string          `json:"reason"`
+	Record        json.RawMessage `json:"record,omitempty"`
+	QuarantinedAt time.Time       `json:"quarantined_at"`
+}
+
+func (q *QuarantinedCountry) ToResponse() QuarantinedCountryResponse {
+	var record json.RawMessage
+	if q.Record.Valid {
+		record = json.RawMessage(q.Record.String)
+	}
+
+	return QuarantinedCountryResponse{
+		Country:       q.CountryName,
+		Currency:      q.Currency.String,
+		Rule:          q.Rule,
+		Reason:        q.Reason,
+		Record:        record,
+		QuarantinedAt: q.QuarantinedAt,
+	}
+}
+
+type QuarantineListResponse struct {
+	RunID     int64                        `json:"run_id"`
+	Total     int                          `json:"total"`
+	Countries []QuarantinedCountryResponse `json:"countries"`
+}
+
+func ToQuarantineListResponse(runID int64, quarantined []QuarantinedCountry) QuarantineListResponse {
+	countries := make([]QuarantinedCountryResponse, len(quarantined))
+	for i, q := range quarantined {
+		countries[i] = q.ToResponse()
+	}
+	return QuarantineListResponse{
+		RunID:     runID,
+		Total:     len(countries),
+		Countries: countries,
+	}
+}
@@ -0,0 +1,86 @@
+package model
+
+import "time"
+
+const (
+	IntervalRaw   = "raw"
+	IntervalHour  = "hour"
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+type RateHistoryDBRow struct {
+	CurrencyCode  string
+	BaseCode      string
+	Rate          float64
+	RateTimestamp time.Time
+}
+
+// RateHistoryPoint is one entry of a rate time series. For raw series Min,
+// Max and Rate are equal and Samples is 1; for bucketed series Rate is the
+// bucket average and Timestamp the bucket start.
+type RateHistoryPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Rate      float64   `json:"rate"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Samples   int       `json:"samples"`
+}
+
+type RateHistoryResponse struct {
+	CurrencyCode string             `json:"currency_code"`
+	BaseCode     *string            `json:"base_code"`
+	From         time.Time          `json:"from"`
+	To           time.Time          `json:"to"`
+	Interval     string             `json:"interval"`
+	Points       []RateHistoryPoint `json:"points"`
+}
+
+// BucketStart returns the start of the interval bucket containing t, in t's
+// location. Weeks start on Monday.
+func BucketStart(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case IntervalDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case IntervalWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return t
+	}
+}
+
+// ToRateHistoryPoints turns rows ordered by timestamp into a series at the
+// requested interval.
+func ToRateHistoryPoints(rows []RateHistoryDBRow, interval string) []RateHistoryPoint {
+	points := []RateHistoryPoint{}
+
+	for _, row := range rows {
+		bucket := BucketStart(row.RateTimestamp, interval)
+
+		last := len(points) - 1
+		if interval != IntervalRaw && last >= 0 && points[last].Timestamp.Equal(bucket) {
+			p := &points[last]
+			p.Rate = (p.Rate*float64(p.Samples) + row.Rate) / float64(p.Samples+1)
+			p.Min = min(p.Min, row.Rate)
+			p.Max = max(p.Max, row.Rate)
+			p.Samples++
+			continue
+		}
+
+		points = append(points, RateHistoryPoint{
+			Timestamp: bucket,
+			Rate:      row.Rate,
+			Min:       row.Rate,
+			Max:       row.Rate,
+			Samples:   1,
+		})
+	}
+
+	return points
+}
@@ -0,0 +1,348 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	RefreshJobQueued    = "queued"
+	RefreshJobRunning   = "running"
+	RefreshJobSucceeded = "succeeded"
+	// RefreshJobPartial means one upstream failed and only the other
+	// part of the data was refreshed.
+	RefreshJobPartial = "partial"
+	RefreshJobFailed  = "failed"
+)
+
+const (
+	RefreshTriggerManual    = "manual"
+	RefreshTriggerScheduled = "scheduled"
+	RefreshTriggerImport    = "import"
+)
+
+// RefreshedParts records which halves of a refresh were applied. Countries
+// and rates come from independent upstreams, so either may be skipped.
+type RefreshedParts struct {
+	Countries bool
+	Rates     bool
+}
+
+// CountryFetchState records what the stored country rows were built from:
+// when the country list was last downloaded in full and with which
+// validation rules, and when a deleted country was last restored.
+type CountryFetchState struct {
+	FetchedAt       sql.NullTime
+	ValidationRules sql.NullString
+	RestoredAt      sql.NullTime
+}
+
+// NeedsFullFetch reports whether a download would add countries that the
+// stored rows lack, so an unchanged upstream cannot be answered from
+// storage: a country or snapshot was restored after the last download, or the
+// validation rules differ from rules.
+func (s *CountryFetchState) NeedsFullFetch(rules string) bool {
+	if !s.FetchedAt.Valid || !s.ValidationRules.Valid || s.ValidationRules.String != rules {
+		return true
+	}
+	return s.RestoredAt.Valid && !s.RestoredAt.Time.Before(s.FetchedAt.Time)
+}
+
+type RefreshJob struct {
+	ID                 int64
+	Status             string
+	Trigger            string
+	QueuedAt           time.Time
+	StartedAt          sql.NullTime
+	FinishedAt         sql.NullTime
+	DurationMs         sql.NullInt64
+	CountriesProcessed sql.NullInt64
+	CountriesRefreshed sql.NullBool
+	RatesRefreshed     sql.NullBool
+	ErrorMessage       sql.NullString
+	ErrorDetails       sql.NullString
+}
+
+type RefreshJobResponse struct {
+	ID                 int64      `json:"id"`
+	Status             string     `json:"status"`
+	Trigger            string     `json:"trigger"`
+	QueuedAt           time.Time  `json:"queued_at"`
+	StartedAt          *time.Time `json:"started_at"`
+	FinishedAt         *time.Time `json:"finished_at"`
+	DurationMs         *int64     `json:"duration_ms"`
+	CountriesProcessed *int64     `json:"countries_processed"`
+	CountriesRefreshed *bool      `json:"countries_refreshed"`
+	RatesRefreshed     *bool      `json:"rates_refreshed"`
+	Error              *string    `json:"error,omitempty"`
+	ErrorDetails       *string    `json:"error_details,omitempty"`
+}
+
+func (j *RefreshJob) ToResponse() RefreshJobResponse {
+	var startedAt, finishedAt *time.Time
+	var durationMs, countriesProcessed *int64
+	var countriesRefreshed, ratesRefreshed *bool
+	var errMessage, errDetails *string
+
+	if j.StartedAt.Valid {
+		startedAt = &j.StartedAt.Time
+	}
+	if j.FinishedAt.Valid {
+		finishedAt = &j.FinishedAt.Time
+	}
+	if j.DurationMs.Valid {
+		durationMs = &j.DurationMs.Int64
+	}
+	if j.CountriesProcessed.Valid {
+		countriesProcessed = &j.CountriesProcessed.Int64
+	}
+	if j.CountriesRefreshed.Valid {
+		countriesRefreshed = &j.CountriesRefreshed.Bool
+	}
+	if j.RatesRefreshed.Valid {
+		ratesRefreshed = &j.RatesRefreshed.Bool
+	}
+	if j.ErrorMessage.Valid {
+		errMessage = &j.ErrorMessage.String
+	}
+	if j.ErrorDetails.Valid {
+		errDetails = &j.ErrorDetails.String
+	}
+
+	return RefreshJobResponse{
+		ID:                 j.ID,
+		Status:             j.Status,
+		Trigger:            j.Trigger,
+		QueuedAt:           j.QueuedAt,
+		StartedAt:          startedAt,
+		FinishedAt:         finishedAt,
+		DurationMs:         durationMs,
+		CountriesProcessed: countriesProcessed,
+		CountriesRefreshed: countriesRefreshed,
+		RatesRefreshed:     ratesRefreshed,
+		Error:              errMessage,
+		ErrorDetails:       errDetails,
+	}
+}
+
+// RefreshRun is the audit record of one execution of the refresh pipeline.
+// Status uses the same values as RefreshJob.
+type RefreshRun struct {
+	ID            int64
+	JobID         sql.NullInt64
+	Trigger       string
+	Status        string
+	StartedAt     time.Time
+	FinishedAt    sql.NullTime
+	DurationMs    sql.NullInt64
+	CountrySource sql.NullString
+	RateSource    sql.NullString
+	// CountriesNotModified and RatesNotModified record that the source
+	// answered a conditional fetch with 304 and stored data was reused.
+	CountriesNotModified sql.NullBool
+	RatesNotModified     sql.NullBool
+	CountriesLatencyMs   sql.NullInt64
+	RatesLatencyMs       sql.NullInt64
+	CountriesRefreshed   sql.NullBool
+	RatesRefreshed       sql.NullBool
+	CountriesInserted    sql.NullInt64
+	CountriesUpdated     sql.NullInt64
+	CountriesUnchanged   sql.NullInt64
+	CountriesRemoved     sql.NullInt64
+	// CountriesQuarantined counts upstream countries rejected by validation.
+	CountriesQuarantined sql.NullInt64
+	ErrorMessage         sql.NullString
+	ErrorDetails         sql.NullString
+}
+
+type RefreshRunResponse struct {
+	ID                   int64      `json:"id"`
+	JobID                *int64     `json:"job_id"`
+	Trigger              string     `json:"trigger"`
+	Status               string     `json:"status"`
+	StartedAt            time.Time  `json:"started_at"`
+	FinishedAt           *time.Time `json:"finished_at"`
+	DurationMs           *int64     `json:"duration_ms"`
+	CountrySource        *string    `json:"country_source"`
+	RateSource           *string    `json:"rate_source"`
+	CountriesNotModified *bool      `json:"countries_not_modified"`
+	RatesNotModified     *bool      `json:"rates_not_modified"`
+	CountriesLatencyMs   *int64     `json:"countries_latency_ms"`
+	RatesLatencyMs       *int64     `json:"rates_latency_ms"`
+	CountriesRefreshed   *bool      `json:"countries_refreshed"`
+	RatesRefreshed       *bool      `json:"rates_refreshed"`
+	CountriesInserted    *int64     `json:"countries_inserted"`
+	CountriesUpdated     *int64     `json:"countries_updated"`
+	CountriesUnchanged   *int64     `json:"countries_unchanged"`
+	CountriesRemoved     *int64     `json:"countries_removed"`
+	CountriesQuarantined *int64     `json:"countries_quarantined"`
+	Error                *string    `json:"error,omitempty"`
+	ErrorDetails         *string    `json:"error_details,omitempty"`
+}
+
+func (r *RefreshRun) ToResponse() RefreshRunResponse {
+	return RefreshRunResponse{
+		ID:                   r.ID,
+		JobID:                nullInt64(r.JobID),
+		Trigger:              r.Trigger,
+		Status:               r.Status,
+		StartedAt:            r.StartedAt,
+		FinishedAt:           nullTime(r.FinishedAt),
+		DurationMs:           nullInt64(r.DurationMs),
+		CountrySource:        nullString(r.CountrySource),
+		RateSource:           nullString(r.RateSource),
+		CountriesNotModified: nullBool(r.CountriesNotModified),
+		RatesNotModified:     nullBool(r.RatesNotModified),
+		CountriesLatencyMs:   nullInt64(r.CountriesLatencyMs),
+		RatesLatencyMs:       nullInt64(r.RatesLatencyMs),
+		CountriesRefreshed:   nullBool(r.CountriesRefreshed),
+		RatesRefreshed:       nullBool(r.RatesRefreshed),
+		CountriesInserted:    nullInt64(r.CountriesInserted),
+		CountriesUpdated:     nullInt64(r.CountriesUpdated),
+		CountriesUnchanged:   nullInt64(r.CountriesUnchanged),
+		CountriesRemoved:     nullInt64(r.CountriesRemoved),
+		CountriesQuarantined: nullInt64(r.CountriesQuarantined),
+		Error:                nullString(r.ErrorMessage),
+		ErrorDetails:         nullString(r.ErrorDetails),
+	}
+}
+
+type RefreshRunListResponse struct {
+	Runs  []RefreshRunResponse `json:"runs"`
+	Page  int                  `json:"page"`
+	Limit int                  `json:"limit"`
+	Total int64                `json:"total"`
+}
+
+// RefreshConflictResponse holds the fields the 409 error body carries when a
+// refresh is requested while another is running. RunID is the running
+// refresh, when it is known.
+type RefreshConflictResponse struct {
+	RunID *int64 `json:"run_id,omitempty"`
+}
+
+func ToRefreshRunResponses(runs []RefreshRun) []RefreshRunResponse {
+	responses := make([]RefreshRunResponse, len(runs))
+	for i, run := range runs {
+		responses[i] = run.ToResponse()
+	}
+	return responses
+}
+
+func nullInt64(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
+
+func nullString(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullBool(v sql.NullBool) *bool {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Bool
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
+
+const (
+	CountryAdded   = "added"
+	CountryRemoved = "removed"
+	CountryChanged = "changed"
+)
+
+// CountryChange is one entry of a refresh diff. Added and removed countries
+// have a single entry with no field; a changed country has one entry per
+// changed field. Delta is set for numeric fields.
+type CountryChange struct {
+	CountryName string
+	ChangeType  string
+	Field       sql.NullString
+	OldValue    sql.NullString
+	NewValue    sql.NullString
+	Delta       sql.NullFloat64
+}
+
+type FieldChangeResponse struct {
+	Field    string   `json:"field"`
+	OldValue *string  `json:"old_value"`
+	NewValue *string  `json:"new_value"`
+	Delta    *float64 `json:"delta,omitempty"`
+}
+
+type CountryDiffResponse struct {
+	Country string                `json:"country"`
+	Fields  []FieldChangeResponse `json:"fields"`
+}
+
+type RefreshDiffSummary struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+}
+
+type RefreshDiffResponse struct {
+	RunID   int64                 `json:"run_id"`
+	Summary RefreshDiffSummary    `json:"summary"`
+	Added   []string              `json:"added"`
+	Removed []string              `json:"removed"`
+	Changed []CountryDiffResponse `json:"changed"`
+}
+
+// ToRefreshDiffResponse groups the stored changes of a run, which must be
+// ordered by country, into added, removed and per-country field changes.
+func ToRefreshDiffResponse(runID int64, changes []CountryChange) RefreshDiffResponse {
+	resp := RefreshDiffResponse{
+		RunID:   runID,
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []CountryDiffResponse{},
+	}
+
+	for _, change := range changes {
+		switch change.ChangeType {
+		case CountryAdded:
+			resp.Added = append(resp.Added, change.CountryName)
+		case CountryRemoved:
+			resp.Removed = append(resp.Removed, change.CountryName)
+		case CountryChanged:
+			last := len(resp.Changed) - 1
+			if last < 0 || resp.Changed[last].Country != change.CountryName {
+				resp.Changed = append(resp.Changed, CountryDiffResponse{Country: change.CountryName})
+				last++
+			}
+			resp.Changed[last].Fields = append(resp.Changed[last].Fields, FieldChangeResponse{
+				Field:    change.Field.String,
+				OldValue: nullString(change.OldValue),
+				NewValue: nullString(change.NewValue),
+				Delta:    nullFloat64(change.Delta),
+			})
+		}
+	}
+
+	resp.Summary = RefreshDiffSummary{
+		Added:   len(resp.Added),
+		Removed: len(resp.Removed),
+		Changed: len(resp.Changed),
+	}
+	return resp
+}
+
+func nullFloat64(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
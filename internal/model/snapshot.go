@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// SnapshotFormatVersion is bumped whenever the layout of snapshot lines
+// changes. The database layout is tracked separately by SchemaVersion.
+const SnapshotFormatVersion = 1
+
+// SnapshotLine is one line of an NDJSON snapshot. The first line carries
+// Header, the last carries Trailer and every line in between is a Row of
+// Table.
+type SnapshotLine struct {
+	Header  *SnapshotHeader  `json:"header,omitempty"`
+	Table   string           `json:"table,omitempty"`
+	Row     map[string]any   `json:"row,omitempty"`
+	Trailer *SnapshotTrailer `json:"trailer,omitempty"`
+}
+
+type SnapshotHeader struct {
+	FormatVersion int `json:"format_version"`
+	// SchemaVersion is the migration level of the exporting database. A
+	// snapshot only restores into a database at the same level.
+	SchemaVersion uint      `json:"schema_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+}
+
+// SnapshotTrailer closes a snapshot. Its row count lets a restore reject a
+// snapshot that was cut short.
+type SnapshotTrailer struct {
+	Rows int64 `json:"rows"`
+}
+
+type SnapshotRestoreResponse struct {
+	SchemaVersion uint             `json:"schema_version"`
+	Tables        map[string]int64 `json:"tables"`
+	Rows          int64            `json:"rows"`
+}
@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/upstream"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// RateModeFallback uses the first rate source that succeeds.
+	RateModeFallback = "fallback"
+	// RateModeMerge uses the first rate source that succeeds and fills
+	// currencies it lacks from the sources after it.
+	RateModeMerge = "merge"
+)
+
+// RateChain combines an ordered list of rate sources into one.
+type RateChain struct {
+	sources []RateSource
+	mode    string
+	logger  *zerolog.Logger
+
+	// last names the sources the previous result was built from, in order.
+	// Until there is one, every source is asked for a body.
+	mu   sync.Mutex
+	last []string
+}
+
+func NewRateChain(sources []RateSource, mode string, logger *zerolog.Logger) *RateChain {
+	if mode == "" {
+		mode = RateModeFallback
+	}
+	return &RateChain{sources: sources, mode: mode, logger: logger}
+}
+
+func (c *RateChain) Name() string {
+	names := make([]string, len(c.sources))
+	for i, source := range c.sources {
+		names[i] = source.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// FetchRates returns upstream.ErrNotModified only when every source the
+// result is built from answered 304 and the previous result was built from
+// the same sources; otherwise stored rates may have come from other sources,
+// so the unchanged ones are asked again for their cached body.
+func (c *RateChain) FetchRates(ctx context.Context) (*model.ExchangeRates, error) {
+	fetched, err := c.fetchSources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(fetched))
+	unchanged := true
+	for i, f := range fetched {
+		names[i] = f.source.Name()
+		unchanged = unchanged && f.rates == nil
+	}
+	if unchanged && slices.Equal(names, c.lastSources()) {
+		return nil, upstream.ErrNotModified
+	}
+
+	usable := make([]fetchedRates, 0, len(fetched))
+	used := make([]string, 0, len(fetched))
+	for i, f := range fetched {
+		if f.rates == nil {
+			rates, err := fetchUsable(upstream.RequireBody(ctx), f.source)
+			if err != nil && i == 0 {
+				return nil, fmt.Errorf("%s: %w", f.source.Name(), err)
+			}
+			if err != nil {
+				c.logger.Warn().Err(err).Str("source", f.source.Name()).Msg("secondary rate source failed, skipping")
+				continue
+			}
+			f.rates = rates
+		}
+		usable = append(usable, f)
+		used = append(used, f.source.Name())
+	}
+
+	primary := usable[0].rates
+	merged := primary
+	if c.mode == RateModeMerge {
+		merged = &model.ExchangeRates{
+			BaseCode:           primary.BaseCode,
+			TimeLastUpdateUnix: primary.TimeLastUpdateUnix,
+			Rates:              maps.Clone(primary.Rates),
+			Source:             primary.Source,
+			Sources:            map[string]string{},
+			Provenance:         maps.Clone(primary.Provenance),
+		}
+		for _, f := range usable[1:] {
+			filled := mergeRates(merged, f.rates)
+			if filled > 0 {
+				c.logger.Info().Str("source", f.source.Name()).Int("currencies", filled).Msg("filled missing exchange rates")
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.last = used
+	c.mu.Unlock()
+	return merged, nil
+}
+
+// fetchedRates is one source's answer; rates is nil when it answered 304.
+type fetchedRates struct {
+	source RateSource
+	rates  *model.ExchangeRates
+}
+
+// fetchSources asks the sources in order until one answers, and in merge
+// mode the sources after it too. Failing secondaries are skipped.
+func (c *RateChain) fetchSources(ctx context.Context) ([]fetchedRates, error) {
+	var (
+		fetched []fetchedRates
+		errList []error
+	)
+	for _, source := range c.sources {
+		if len(fetched) > 0 && c.mode != RateModeMerge {
+			break
+		}
+
+		rates, err := fetchUsable(ctx, source)
+		if err != nil && !errors.Is(err, upstream.ErrNotModified) {
+			if len(fetched) == 0 {
+				c.logger.Warn().Err(err).Str("source", source.Name()).Msg("rate source failed, trying next")
+				errList = append(errList, fmt.Errorf("%s: %w", source.Name(), err))
+			} else {
+				c.logger.Warn().Err(err).Str("source", source.Name()).Msg("secondary rate source failed, skipping")
+			}
+			continue
+		}
+		fetched = append(fetched, fetchedRates{source: source, rates: rates})
+	}
+
+	if len(fetched) == 0 {
+		return nil, errors.Join(errList...)
+	}
+	return fetched, nil
+}
+
+func (c *RateChain) lastSources() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// fetchUsable fetches rates and rejects empty responses so an upstream that
+// answers with nothing is treated like one that is down.
+func fetchUsable(ctx context.Context, source RateSource) (*model.ExchangeRates, error) {
+	rates, err := source.FetchRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rates == nil || len(rates.Rates) == 0 {
+		return nil, errors.New("returned no exchange rates")
+	}
+	if rates.Source == "" {
+		rates.Source = source.Name()
+	}
+	return rates, nil
+}
+
+// mergeRates copies currencies missing from dst out of src, converting them
+// to dst's base when the two differ. It returns how many were added.
+func mergeRates(dst, src *model.ExchangeRates) int {
+	factor := 1.0
+	if src.BaseCode != "" && dst.BaseCode != "" && !strings.EqualFold(src.BaseCode, dst.BaseCode) {
+		// src quotes X per src base; dst wants X per dst base.
+		dstBaseInSrc, ok := src.Rates[strings.ToUpper(dst.BaseCode)]
+		if !ok || dstBaseInSrc == 0 {
+			return 0
+		}
+		factor = 1 / dstBaseInSrc
+	}
+
+	filled := 0
+	for code, rate := range src.Rates {
+		if _, ok := dst.Rates[code]; ok {
+			continue
+		}
+		dst.Rates[code] = rate * factor
+		dst.Sources[code] = src.Source
+		filled++
+	}
+	if filled > 0 {
+		if dst.Provenance == nil {
+			dst.Provenance = map[string]model.Provenance{}
+		}
+		maps.Copy(dst.Provenance, src.Provenance)
+	}
+	return filled
+}
@@ -0,0 +1,245 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/upstream"
+	"github.com/rs/zerolog"
+)
+
+type stubRateSource struct {
+	name  string
+	rates *model.ExchangeRates
+	err   error
+}
+
+func (s stubRateSource) Name() string { return s.name }
+
+func (s stubRateSource) FetchRates(context.Context) (*model.ExchangeRates, error) {
+	return s.rates, s.err
+}
+
+func TestMergeRates(t *testing.T) {
+	tests := []struct {
+		name       string
+		dst, src   *model.ExchangeRates
+		wantFilled int
+		want       map[string]float64
+	}{
+		{
+			name:       "same base fills missing only",
+			dst:        &model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"USD": 1, "EUR": 0.9}},
+			src:        &model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"EUR": 0.95, "GBP": 0.8}},
+			wantFilled: 1,
+			want:       map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0.8},
+		},
+		{
+			name:       "different base is converted through the dst base",
+			dst:        &model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"USD": 1}},
+			src:        &model.ExchangeRates{BaseCode: "EUR", Rates: map[string]float64{"EUR": 1, "USD": 1.25, "GBP": 0.85}},
+			wantFilled: 2,
+			want:       map[string]float64{"USD": 1, "EUR": 0.8, "GBP": 0.68},
+		},
+		{
+			name:       "base compared case-insensitively",
+			dst:        &model.ExchangeRates{BaseCode: "usd", Rates: map[string]float64{"USD": 1}},
+			src:        &model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"NGN": 1500}},
+			wantFilled: 1,
+			want:       map[string]float64{"USD": 1, "NGN": 1500},
+		},
+		{
+			name:       "src without the dst base contributes nothing",
+			dst:        &model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"USD": 1}},
+			src:        &model.ExchangeRates{BaseCode: "EUR", Rates: map[string]float64{"EUR": 1, "GBP": 0.85}},
+			wantFilled: 0,
+			want:       map[string]float64{"USD": 1},
+		},
+		{
+			name:       "zero dst base rate in src contributes nothing",
+			dst:        &model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"USD": 1}},
+			src:        &model.ExchangeRates{BaseCode: "EUR", Rates: map[string]float64{"USD": 0, "GBP": 0.85}},
+			wantFilled: 0,
+			want:       map[string]float64{"USD": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.dst.Source, tt.dst.Sources = "primary", map[string]string{}
+			tt.src.Source = "secondary"
+
+			if filled := mergeRates(tt.dst, tt.src); filled != tt.wantFilled {
+				t.Errorf("filled = %d, want %d", filled, tt.wantFilled)
+			}
+			assertRates(t, tt.dst.Rates, tt.want)
+			if len(tt.dst.Sources) != tt.wantFilled {
+				t.Errorf("Sources = %v, want %d filled currencies", tt.dst.Sources, tt.wantFilled)
+			}
+			for code, source := range tt.dst.Sources {
+				if source != "secondary" {
+					t.Errorf("Sources[%s] = %q, want secondary", code, source)
+				}
+			}
+		})
+	}
+}
+
+func TestRateChainFetchRates(t *testing.T) {
+	down := stubRateSource{name: "down", err: errors.New("unavailable")}
+	empty := stubRateSource{name: "empty", rates: &model.ExchangeRates{BaseCode: "USD"}}
+	primary := stubRateSource{name: "primary", rates: &model.ExchangeRates{
+		BaseCode: "USD",
+		Rates:    map[string]float64{"USD": 1, "EUR": 0.9},
+	}}
+	secondary := stubRateSource{name: "secondary", rates: &model.ExchangeRates{
+		BaseCode: "EUR",
+		Rates:    map[string]float64{"EUR": 1, "USD": 1.25, "GBP": 0.85},
+	}}
+
+	tests := []struct {
+		name       string
+		mode       string
+		sources    []RateSource
+		want       map[string]float64
+		wantSource string
+		wantErr    bool
+	}{
+		{
+			name:       "fallback skips failed and empty sources",
+			mode:       RateModeFallback,
+			sources:    []RateSource{down, empty, primary, secondary},
+			want:       map[string]float64{"USD": 1, "EUR": 0.9},
+			wantSource: "primary",
+		},
+		{
+			name:       "merge fills from later sources",
+			mode:       RateModeMerge,
+			sources:    []RateSource{down, primary, secondary},
+			want:       map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0.68},
+			wantSource: "primary",
+		},
+		{
+			name:    "all sources failing is an error",
+			mode:    RateModeMerge,
+			sources: []RateSource{down, empty},
+			wantErr: true,
+		},
+	}
+
+	logger := zerolog.Nop()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := NewRateChain(tt.sources, tt.mode, &logger).FetchRates(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("FetchRates succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchRates: %v", err)
+			}
+			assertRates(t, rates.Rates, tt.want)
+			if rates.Source != tt.wantSource {
+				t.Errorf("Source = %q, want %q", rates.Source, tt.wantSource)
+			}
+		})
+	}
+
+	if _, ok := primary.rates.Rates["GBP"]; ok {
+		t.Error("merging modified the primary source's rates")
+	}
+}
+
+// conditionalRateSource answers 304 while unchanged, unless asked for a body.
+type conditionalRateSource struct {
+	name      string
+	rates     *model.ExchangeRates
+	unchanged bool
+	bodies    int
+}
+
+func (s *conditionalRateSource) Name() string { return s.name }
+
+func (s *conditionalRateSource) FetchRates(ctx context.Context) (*model.ExchangeRates, error) {
+	if s.unchanged && !upstream.RequiresBody(ctx) {
+		return nil, upstream.ErrNotModified
+	}
+	s.bodies++
+	return s.rates, nil
+}
+
+func TestRateChainNotModified(t *testing.T) {
+	primaryUpdated := time.Unix(1700000000, 0)
+	secondaryUpdated := time.Unix(1690000000, 0)
+	primary := &conditionalRateSource{name: "primary", rates: &model.ExchangeRates{
+		BaseCode:   "USD",
+		Rates:      map[string]float64{"USD": 1, "EUR": 0.9},
+		Source:     "primary",
+		Provenance: map[string]model.Provenance{"primary": {Source: "primary", UpdatedAt: primaryUpdated}},
+	}}
+	secondary := &conditionalRateSource{name: "secondary", rates: &model.ExchangeRates{
+		BaseCode:   "USD",
+		Rates:      map[string]float64{"GBP": 0.8},
+		Source:     "secondary",
+		Provenance: map[string]model.Provenance{"secondary": {Source: "secondary", UpdatedAt: secondaryUpdated}},
+	}}
+
+	logger := zerolog.Nop()
+	chain := NewRateChain([]RateSource{primary, secondary}, RateModeMerge, &logger)
+	ctx := context.Background()
+
+	// Nothing is known about earlier results: unchanged sources hand over
+	// their cached body.
+	primary.unchanged, secondary.unchanged = true, true
+	rates, err := chain.FetchRates(ctx)
+	if err != nil {
+		t.Fatalf("first FetchRates: %v", err)
+	}
+	assertRates(t, rates.Rates, map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0.8})
+	if p, _ := rates.ProvenanceOf("GBP"); !p.UpdatedAt.Equal(secondaryUpdated) {
+		t.Errorf("GBP updated at %v, want the secondary's %v", p.UpdatedAt, secondaryUpdated)
+	}
+	if p, _ := rates.ProvenanceOf("EUR"); !p.UpdatedAt.Equal(primaryUpdated) {
+		t.Errorf("EUR updated at %v, want the primary's %v", p.UpdatedAt, primaryUpdated)
+	}
+
+	// Same sources, all unchanged: nothing to do.
+	if _, err := chain.FetchRates(ctx); !errors.Is(err, upstream.ErrNotModified) {
+		t.Fatalf("FetchRates with nothing changed: err = %v, want ErrNotModified", err)
+	}
+	if primary.bodies != 1 || secondary.bodies != 1 {
+		t.Errorf("bodies fetched = %d, %d; want 1, 1", primary.bodies, secondary.bodies)
+	}
+
+	// Only the secondary changed: the primary's body is needed to merge.
+	secondary.unchanged = false
+	secondary.rates.Rates["JPY"] = 150
+	rates, err = chain.FetchRates(ctx)
+	if err != nil {
+		t.Fatalf("FetchRates with a changed secondary: %v", err)
+	}
+	assertRates(t, rates.Rates, map[string]float64{"USD": 1, "EUR": 0.9, "GBP": 0.8, "JPY": 150})
+	if primary.bodies != 2 {
+		t.Errorf("primary bodies fetched = %d, want 2", primary.bodies)
+	}
+}
+
+func assertRates(t *testing.T, got, want map[string]float64) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("rates = %v, want %v", got, want)
+		return
+	}
+	for code, rate := range want {
+		if math.Abs(got[code]-rate) > 1e-9 {
+			t.Errorf("rate[%s] = %v, want %v", code, got[code], rate)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// CountryFileSource reads countries from a local file. JSON files use the
+// format the restcountries v2 API returns; see DecodeCountries for NDJSON and
+// CSV.
+type CountryFileSource struct {
+	path string
+}
+
+func NewCountryFileSource(cfg config.SourceConfig) *CountryFileSource {
+	return &CountryFileSource{path: cfg.Path}
+}
+
+func (s *CountryFileSource) Name() string {
+	return CountryFile
+}
+
+func (s *CountryFileSource) URL() string {
+	return fileURL(s.path)
+}
+
+func (s *CountryFileSource) FetchCountries(ctx context.Context) ([]model.Country, error) {
+	format, err := FileFormat(s.path)
+	if err != nil {
+		return nil, err
+	}
+	body, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read countries file: %w", err)
+	}
+
+	countries, err := DecodeCountries(body, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse countries file: %w", err)
+	}
+	return countries, nil
+}
+
+// RateFileSource reads exchange rates from a local file. JSON files use the
+// format open.er-api.com returns; see DecodeRates for NDJSON and CSV.
+type RateFileSource struct {
+	path string
+}
+
+func NewRateFileSource(cfg config.SourceConfig) *RateFileSource {
+	return &RateFileSource{path: cfg.Path}
+}
+
+func (s *RateFileSource) Name() string {
+	return RateFile
+}
+
+func (s *RateFileSource) URL() string {
+	return fileURL(s.path)
+}
+
+func (s *RateFileSource) FetchRates(ctx context.Context) (*model.ExchangeRates, error) {
+	format, err := FileFormat(s.path)
+	if err != nil {
+		return nil, err
+	}
+	body, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	rates, err := DecodeRates(body, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+	rates.Source = RateFile
+	rates.Provenance = provenance(RateFile, s.URL(), rates.TimeLastUpdateUnix)
+	return rates, nil
+}
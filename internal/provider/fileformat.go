@@ -0,0 +1,249 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// File formats accepted by the file providers, picked by file extension.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// FileFormat returns the format of the file at path based on its extension.
+func FileFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("unsupported file type %q, expected .json, .ndjson, .jsonl or .csv", filepath.Ext(path))
+	}
+}
+
+// DecodeCountries parses countries in the given format.
+//
+// JSON is an array in the restcountries v2 shape and NDJSON is one such
+// country per line. CSV needs a header row with at least a name column;
+// alpha2_code, alpha3_code, numeric_code, capital, region, subregion,
+// population, area, flag, currency_code, currency_name and currency_symbol
+// are optional. A country with several currencies repeats its row once per
+// currency.
+func DecodeCountries(body []byte, format string) ([]model.Country, error) {
+	switch format {
+	case FormatJSON:
+		var countries []model.Country
+		if err := json.Unmarshal(body, &countries); err != nil {
+			return nil, err
+		}
+		return countries, nil
+	case FormatNDJSON:
+		var countries []model.Country
+		err := eachLine(body, func(line []byte) error {
+			var country model.Country
+			if err := json.Unmarshal(line, &country); err != nil {
+				return err
+			}
+			countries = append(countries, country)
+			return nil
+		})
+		return countries, err
+	case FormatCSV:
+		return decodeCountriesCSV(body)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// DecodeRates parses exchange rates in the given format.
+//
+// JSON is an object in the open.er-api.com shape and NDJSON is one such
+// object per line, later lines overriding earlier ones. CSV needs a header
+// row with code and rate columns and may carry a base_code column.
+func DecodeRates(body []byte, format string) (*model.ExchangeRates, error) {
+	switch format {
+	case FormatJSON:
+		var rates model.ExchangeRates
+		if err := json.Unmarshal(body, &rates); err != nil {
+			return nil, err
+		}
+		return &rates, nil
+	case FormatNDJSON:
+		rates := &model.ExchangeRates{Rates: map[string]float64{}}
+		err := eachLine(body, func(line []byte) error {
+			var part model.ExchangeRates
+			if err := json.Unmarshal(line, &part); err != nil {
+				return err
+			}
+			if part.BaseCode != "" {
+				rates.BaseCode = part.BaseCode
+			}
+			if part.TimeLastUpdateUnix != 0 {
+				rates.TimeLastUpdateUnix = part.TimeLastUpdateUnix
+			}
+			for code, rate := range part.Rates {
+				rates.Rates[code] = rate
+			}
+			return nil
+		})
+		return rates, err
+	case FormatCSV:
+		return decodeRatesCSV(body)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// eachLine calls fn for every non-blank line of body.
+func eachLine(body []byte, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// csvRecords reads a CSV file with a header row and returns its records
+// along with the position of each header, keyed by lower-cased name.
+func csvRecords(body []byte, required ...string) ([][]string, map[string]int, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("missing header row")
+		}
+		return nil, nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("missing %q column", name)
+		}
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	return records, columns, nil
+}
+
+func decodeCountriesCSV(body []byte) ([]model.Country, error) {
+	records, columns, err := csvRecords(body, "name")
+	if err != nil {
+		return nil, err
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var countries []model.Country
+	index := make(map[string]int)
+	for n, record := range records {
+		// Line numbers count the header row.
+		name := field(record, "name")
+		if name == "" {
+			return nil, fmt.Errorf("line %d: empty name", n+2)
+		}
+
+		i, seen := index[name]
+		if !seen {
+			var population int64
+			if value := field(record, "population"); value != "" {
+				population, err = strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid population %q", n+2, value)
+				}
+			}
+			var area float64
+			if value := field(record, "area"); value != "" {
+				area, err = strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid area %q", n+2, value)
+				}
+			}
+			countries = append(countries, model.Country{
+				Name:        name,
+				Alpha2Code:  field(record, "alpha2_code"),
+				Alpha3Code:  field(record, "alpha3_code"),
+				NumericCode: field(record, "numeric_code"),
+				Capital:     field(record, "capital"),
+				Region:      field(record, "region"),
+				Subregion:   field(record, "subregion"),
+				Population:  population,
+				Area:        area,
+				FlagURL:     field(record, "flag"),
+			})
+			i = len(countries) - 1
+			index[name] = i
+		}
+
+		if code := field(record, "currency_code"); code != "" {
+			countries[i].Currencies = append(countries[i].Currencies, model.CountryCurrency{
+				Code:   code,
+				Name:   field(record, "currency_name"),
+				Symbol: field(record, "currency_symbol"),
+			})
+		}
+	}
+	return countries, nil
+}
+
+func decodeRatesCSV(body []byte) (*model.ExchangeRates, error) {
+	records, columns, err := csvRecords(body, "code", "rate")
+	if err != nil {
+		return nil, err
+	}
+
+	rates := &model.ExchangeRates{Rates: make(map[string]float64, len(records))}
+	for n, record := range records {
+		if len(record) <= columns["code"] || len(record) <= columns["rate"] {
+			return nil, fmt.Errorf("line %d: missing code or rate", n+2)
+		}
+		code := strings.TrimSpace(record[columns["code"]])
+		value := strings.TrimSpace(record[columns["rate"]])
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || code == "" {
+			return nil, fmt.Errorf("line %d: invalid rate %q for %q", n+2, value, code)
+		}
+		rates.Rates[code] = rate
+
+		if i, ok := columns["base_code"]; ok && i < len(record) && record[i] != "" {
+			rates.BaseCode = strings.TrimSpace(record[i])
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/upstream"
+)
+
+const defaultFrankfurterURL = "https://api.frankfurter.app/latest?from="
+
+// FrankfurterSource reads ECB reference rates from api.frankfurter.app. It
+// covers fewer currencies than open.er-api.com, so it is best used as a
+// fallback or merge secondary.
+type FrankfurterSource struct {
+	url    string
+	client *upstream.Client
+}
+
+type frankfurterResponse struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func NewFrankfurterSource(cfg config.SourceConfig, base string, client *upstream.Client) *FrankfurterSource {
+	url := cfg.URL
+	if url == "" {
+		url = defaultFrankfurterURL + base
+	}
+	return &FrankfurterSource{url: url, client: client}
+}
+
+func (s *FrankfurterSource) Name() string {
+	return Frankfurter
+}
+
+func (s *FrankfurterSource) URL() string {
+	return s.url
+}
+
+func (s *FrankfurterSource) FetchRates(ctx context.Context) (*model.ExchangeRates, error) {
+	body, err := s.client.Get(ctx, s.url)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp frankfurterResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal exchange rate data: %w", err)
+	}
+
+	rates := &model.ExchangeRates{
+		BaseCode: resp.Base,
+		Rates:    resp.Rates,
+		Source:   Frankfurter,
+	}
+	// Frankfurter omits the base itself from rates.
+	if rates.Rates != nil && resp.Base != "" {
+		rates.Rates[resp.Base] = 1
+	}
+	if date, err := time.Parse(time.DateOnly, resp.Date); err == nil {
+		rates.TimeLastUpdateUnix = date.Unix()
+	}
+	rates.Provenance = provenance(Frankfurter, s.url, rates.TimeLastUpdateUnix)
+	return rates, nil
+}
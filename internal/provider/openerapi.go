@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/upstream"
+)
+
+const defaultOpenERAPIURL = "https://open.er-api.com/v6/latest/"
+
+// OpenERAPISource reads exchange rates from open.er-api.com, quoted against
+// base unless a configured URL says otherwise.
+type OpenERAPISource struct {
+	url    string
+	client *upstream.Client
+}
+
+func NewOpenERAPISource(cfg config.SourceConfig, base string, client *upstream.Client) *OpenERAPISource {
+	url := cfg.URL
+	if url == "" {
+		url = defaultOpenERAPIURL + base
+	}
+	return &OpenERAPISource{url: url, client: client}
+}
+
+func (s *OpenERAPISource) Name() string {
+	return OpenERAPI
+}
+
+func (s *OpenERAPISource) URL() string {
+	return s.url
+}
+
+func (s *OpenERAPISource) FetchRates(ctx context.Context) (*model.ExchangeRates, error) {
+	body, err := s.client.Get(ctx, s.url)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := model.ExchangeRates{Source: OpenERAPI}
+	if err := json.Unmarshal(body, &rates); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal exchange rate data: %w", err)
+	}
+	rates.Provenance = provenance(OpenERAPI, s.url, rates.TimeLastUpdateUnix)
+	return &rates, nil
+}
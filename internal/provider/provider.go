@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/upstream"
+	"github.com/rs/zerolog"
+)
+
+// CountrySource supplies the list of countries to store on refresh. URL
+// says where the countries are read from.
+type CountrySource interface {
+	Name() string
+	URL() string
+	FetchCountries(ctx context.Context) ([]model.Country, error)
+}
+
+// RateSource supplies exchange rates keyed by ISO 4217 currency code. The
+// rates it returns carry their own Provenance.
+type RateSource interface {
+	Name() string
+	FetchRates(ctx context.Context) (*model.ExchangeRates, error)
+}
+
+const (
+	RestCountries = "restcountries"
+	// RestCountriesV3 is the restcountries.com v3.1 API.
+	RestCountriesV3 = "restcountriesv3"
+	OpenERAPI       = "openerapi"
+	Frankfurter     = "frankfurter"
+	CountryFile     = "countryfile"
+	RateFile        = "ratefile"
+)
+
+// NewCountrySource builds the country provider registered under name using
+// its entry in the SOURCE_* configuration. An empty name selects restcountries.
+func NewCountrySource(name string, sources map[string]config.SourceConfig, cacheDir string, logger *zerolog.Logger) (CountrySource, error) {
+	if name == "" {
+		name = RestCountries
+	}
+	cfg := sources[name]
+
+	switch name {
+	case RestCountries:
+		return NewRestCountriesSource(cfg, newClient(name, cfg, cacheDir, logger)), nil
+	case RestCountriesV3:
+		return NewRestCountriesV3Source(cfg, newClient(name, cfg, cacheDir, logger)), nil
+	case CountryFile:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("country source %q requires a path", name)
+		}
+		return NewCountryFileSource(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown country source %q", name)
+	}
+}
+
+// NewRateSource builds the exchange-rate provider registered under name
+// using its entry in the SOURCE_* configuration, asking for rates against
+// base. An empty name selects open.er-api.com.
+func NewRateSource(name, base string, sources map[string]config.SourceConfig, cacheDir string, logger *zerolog.Logger) (RateSource, error) {
+	if name == "" {
+		name = OpenERAPI
+	}
+	cfg := sources[name]
+
+	switch name {
+	case OpenERAPI:
+		return NewOpenERAPISource(cfg, base, newClient(name, cfg, cacheDir, logger)), nil
+	case Frankfurter:
+		return NewFrankfurterSource(cfg, base, newClient(name, cfg, cacheDir, logger)), nil
+	case RateFile:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("rate source %q requires a path", name)
+		}
+		return NewRateFileSource(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown rate source %q", name)
+	}
+}
+
+// NewRateSources builds the rate providers named in order. A single name
+// yields that provider; several are combined into a RateChain using mode.
+func NewRateSources(names []string, mode, base string, sources map[string]config.SourceConfig, cacheDir string, logger *zerolog.Logger) (RateSource, error) {
+	switch mode {
+	case "", RateModeFallback, RateModeMerge:
+	default:
+		return nil, fmt.Errorf("unknown rate mode %q", mode)
+	}
+
+	if len(names) <= 1 {
+		name := ""
+		if len(names) == 1 {
+			name = strings.TrimSpace(names[0])
+		}
+		return NewRateSource(name, base, sources, cacheDir, logger)
+	}
+
+	chain := make([]RateSource, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if seen[name] {
+			return nil, fmt.Errorf("rate source %q listed more than once", name)
+		}
+		seen[name] = true
+
+		source, err := NewRateSource(name, base, sources, cacheDir, logger)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, source)
+	}
+	return NewRateChain(chain, mode, logger), nil
+}
+
+// newClient builds the HTTP client of an upstream provider. Responses are
+// cached under cacheDir, or upstream.DefaultCacheDir when it is empty,
+// unless the source disables caching.
+func newClient(name string, cfg config.SourceConfig, cacheDir string, logger *zerolog.Logger) *upstream.Client {
+	if cacheDir == "" {
+		cacheDir = upstream.DefaultCacheDir
+	}
+	if cfg.DisableCache {
+		cacheDir = ""
+	}
+
+	return upstream.NewClient(name, upstream.Options{
+		Timeout:      time.Duration(cfg.Timeout) * time.Second,
+		MaxRetries:   cfg.MaxRetries,
+		BackoffBase:  time.Duration(cfg.BackoffBaseMs) * time.Millisecond,
+		BackoffMax:   time.Duration(cfg.BackoffMaxMs) * time.Millisecond,
+		MaxBodyBytes: cfg.MaxBodyBytes,
+		CacheDir:     cacheDir,
+	}, logger)
+}
+
+// provenance describes rates just fetched by source from url. updatedUnix
+// is the upstream's own timestamp, 0 when it gives none.
+func provenance(source, url string, updatedUnix int64) map[string]model.Provenance {
+	p := model.Provenance{Source: source, URL: url, FetchedAt: time.Now()}
+	if updatedUnix > 0 {
+		p.UpdatedAt = time.Unix(updatedUnix, 0)
+	}
+	return map[string]model.Provenance{source: p}
+}
+
+// fileURL returns a file:// URL for a local path.
+func fileURL(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/upstream"
+)
+
+// Like v3.1, the v2 /all endpoint accepts at most ten fields, so the default
+// source asks for the rest in a second request and merges the two by
+// alpha3Code.
+const (
+	defaultRestCountriesURL        = "https://restcountries.com/v2/all?fields=name,alpha2Code,alpha3Code,capital,region,subregion,population,area,flag,currencies"
+	defaultRestCountriesDetailsURL = "https://restcountries.com/v2/all?fields=alpha3Code,numericCode,languages,timezones,callingCodes,borders,altSpellings"
+)
+
+// RestCountriesSource reads countries from the restcountries.com v2 API. A
+// configured URL is fetched on its own and should ask for every field it
+// needs.
+type RestCountriesSource struct {
+	url        string
+	detailsURL string
+	client     *upstream.Client
+}
+
+func NewRestCountriesSource(cfg config.SourceConfig, client *upstream.Client) *RestCountriesSource {
+	if cfg.URL != "" {
+		return &RestCountriesSource{url: cfg.URL, client: client}
+	}
+	return &RestCountriesSource{
+		url:        defaultRestCountriesURL,
+		detailsURL: defaultRestCountriesDetailsURL,
+		client:     client,
+	}
+}
+
+func (s *RestCountriesSource) Name() string {
+	return RestCountries
+}
+
+func (s *RestCountriesSource) URL() string {
+	return s.url
+}
+
+// FetchCountries reports upstream.ErrNotModified only when neither request
+// returned new data; when just one did, the other is read from the cache.
+func (s *RestCountriesSource) FetchCountries(ctx context.Context) ([]model.Country, error) {
+	body, detailsBody, err := fetchWithDetails(ctx, s.client, s.url, s.detailsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var countries []model.Country
+	if err := json.Unmarshal(body, &countries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal countries data: %w", err)
+	}
+	if detailsBody != nil {
+		var details []model.Country
+		if err := json.Unmarshal(detailsBody, &details); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal country details data: %w", err)
+		}
+		mergeCountryDetails(countries, details)
+	}
+	return countries, nil
+}
+
+// fetchWithDetails gets url and, when detailsURL is set, detailsURL too. The
+// pair is unchanged only when both answer 304; otherwise the unchanged one
+// is read again from the cache so the two bodies can be merged.
+func fetchWithDetails(ctx context.Context, client *upstream.Client, url, detailsURL string) (body, detailsBody []byte, err error) {
+	body, err = client.Get(ctx, url)
+	notModified := errors.Is(err, upstream.ErrNotModified)
+	if err != nil && (!notModified || detailsURL == "") {
+		return nil, nil, err
+	}
+	if detailsURL == "" {
+		return body, nil, nil
+	}
+
+	detailsCtx := ctx
+	if !notModified {
+		detailsCtx = upstream.RequireBody(ctx)
+	}
+	if detailsBody, err = client.Get(detailsCtx, detailsURL); err != nil {
+		return nil, nil, err
+	}
+	if notModified {
+		if body, err = client.Get(upstream.RequireBody(ctx), url); err != nil {
+			return nil, nil, err
+		}
+	}
+	return body, detailsBody, nil
+}
+
+// mergeCountryDetails copies the fields of the second v2 request onto the
+// countries of the first with the same alpha3Code. Countries without
+// details keep them empty.
+func mergeCountryDetails(countries, details []model.Country) {
+	byCode := make(map[string]*model.Country, len(details))
+	for i := range details {
+		byCode[strings.ToUpper(details[i].Alpha3Code)] = &details[i]
+	}
+
+	for i := range countries {
+		d, ok := byCode[strings.ToUpper(countries[i].Alpha3Code)]
+		if !ok || countries[i].Alpha3Code == "" {
+			continue
+		}
+		countries[i].NumericCode = d.NumericCode
+		countries[i].Languages = d.Languages
+		countries[i].Timezones = d.Timezones
+		countries[i].CallingCodes = d.CallingCodes
+		countries[i].Borders = d.Borders
+		countries[i].AltSpellings = d.AltSpellings
+	}
+}
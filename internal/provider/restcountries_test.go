@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/upstream"
+	"github.com/rs/zerolog"
+)
+
+func TestMergeCountryDetails(t *testing.T) {
+	countries := []model.Country{
+		{Name: "Belgium", Alpha3Code: "BEL"},
+		{Name: "Atlantis", Alpha3Code: "ATL"},
+		{Name: "Nowhere"},
+	}
+	details := []model.Country{
+		{Alpha3Code: "bel", NumericCode: "056", CallingCodes: []string{"32"}, Borders: []string{"FRA", "DEU"}},
+		{NumericCode: "999"},
+	}
+
+	mergeCountryDetails(countries, details)
+
+	if countries[0].NumericCode != "056" || !reflect.DeepEqual(countries[0].CallingCodes, []string{"32"}) ||
+		!reflect.DeepEqual(countries[0].Borders, []string{"FRA", "DEU"}) {
+		t.Errorf("Belgium = %+v, want the details merged by alpha3Code", countries[0])
+	}
+	if countries[1].NumericCode != "" {
+		t.Errorf("country without details = %+v, want no details", countries[1])
+	}
+	if countries[2].NumericCode != "" {
+		t.Errorf("country without alpha3Code = %+v, want no details", countries[2])
+	}
+}
+
+func TestRestCountriesFetchNotModified(t *testing.T) {
+	handler := &detailsServer{
+		main:    `[{"name":"Belgium","alpha3Code":"BEL","population":11555997}]`,
+		details: `[{"alpha3Code":"BEL","numericCode":"056"}]`,
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	source := &RestCountriesSource{
+		url:        server.URL + "/main",
+		detailsURL: server.URL + "/details",
+		client:     upstream.NewClient("test", upstream.Options{CacheDir: t.TempDir(), MaxRetries: -1}, &logger),
+	}
+	ctx := context.Background()
+
+	countries, err := source.FetchCountries(ctx)
+	if err != nil {
+		t.Fatalf("first FetchCountries: %v", err)
+	}
+	if len(countries) != 1 || countries[0].Name != "Belgium" || countries[0].NumericCode != "056" {
+		t.Errorf("countries = %+v, want Belgium with its numeric code", countries)
+	}
+	if _, err := source.FetchCountries(ctx); !errors.Is(err, upstream.ErrNotModified) {
+		t.Fatalf("FetchCountries with nothing changed: err = %v, want ErrNotModified", err)
+	}
+
+	// Only the details changed: the first request's body comes from cache.
+	handler.details = `[{"alpha3Code":"BEL","numericCode":"056","borders":["FRA"]}]`
+	countries, err = source.FetchCountries(ctx)
+	if err != nil {
+		t.Fatalf("FetchCountries with new details: %v", err)
+	}
+	if len(countries) != 1 || countries[0].Population != 11555997 || !reflect.DeepEqual(countries[0].Borders, []string{"FRA"}) {
+		t.Errorf("countries = %+v, want Belgium with the new borders", countries)
+	}
+
+	// Only the first request changed: the details come from cache.
+	handler.main = `[{"name":"Belgium","alpha3Code":"BEL","population":11600000}]`
+	countries, err = source.FetchCountries(ctx)
+	if err != nil {
+		t.Fatalf("FetchCountries with new countries: %v", err)
+	}
+	if len(countries) != 1 || countries[0].Population != 11600000 || countries[0].NumericCode != "056" {
+		t.Errorf("countries = %+v, want the new population with the cached details", countries)
+	}
+}
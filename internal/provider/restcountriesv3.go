@@ -0,0 +1,237 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/upstream"
+)
+
+// The v3.1 /all endpoint accepts at most ten fields, so the default source
+// asks for the rest in a second request and merges the two by cca3.
+const (
+	defaultRestCountriesV3URL        = "https://restcountries.com/v3.1/all?fields=name,cca2,cca3,capital,region,subregion,population,area,flags,currencies"
+	defaultRestCountriesV3DetailsURL = "https://restcountries.com/v3.1/all?fields=cca3,ccn3,languages,timezones,idd,borders,altSpellings"
+)
+
+// RestCountriesV3Source reads countries from the restcountries.com v3.1 API
+// and converts them to the v2-shaped internal model. A configured URL is
+// fetched on its own and should ask for every field it needs.
+type RestCountriesV3Source struct {
+	url        string
+	detailsURL string
+	client     *upstream.Client
+}
+
+func NewRestCountriesV3Source(cfg config.SourceConfig, client *upstream.Client) *RestCountriesV3Source {
+	if cfg.URL != "" {
+		return &RestCountriesV3Source{url: cfg.URL, client: client}
+	}
+	return &RestCountriesV3Source{
+		url:        defaultRestCountriesV3URL,
+		detailsURL: defaultRestCountriesV3DetailsURL,
+		client:     client,
+	}
+}
+
+func (s *RestCountriesV3Source) Name() string {
+	return RestCountriesV3
+}
+
+func (s *RestCountriesV3Source) URL() string {
+	return s.url
+}
+
+// FetchCountries reports upstream.ErrNotModified only when neither request
+// returned new data; when just one did, the other is read from the cache.
+func (s *RestCountriesV3Source) FetchCountries(ctx context.Context) ([]model.Country, error) {
+	body, detailsBody, err := fetchWithDetails(ctx, s.client, s.url, s.detailsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []restCountryV3
+	if err := json.Unmarshal(body, &records); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal countries data: %w", err)
+	}
+	if detailsBody != nil {
+		var details []restCountryV3
+		if err := json.Unmarshal(detailsBody, &details); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal country details data: %w", err)
+		}
+		mergeDetails(records, details)
+	}
+
+	countries := make([]model.Country, len(records))
+	for i, record := range records {
+		countries[i] = record.toCountry()
+	}
+	return countries, nil
+}
+
+// mergeDetails copies the fields of the second v3.1 request onto the records
+// of the first with the same cca3. Records without details keep them empty.
+func mergeDetails(records, details []restCountryV3) {
+	byCode := make(map[string]*restCountryV3, len(details))
+	for i := range details {
+		byCode[strings.ToUpper(details[i].CCA3)] = &details[i]
+	}
+
+	for i := range records {
+		d, ok := byCode[strings.ToUpper(records[i].CCA3)]
+		if !ok || records[i].CCA3 == "" {
+			continue
+		}
+		records[i].CCN3 = d.CCN3
+		records[i].Languages = d.Languages
+		records[i].Timezones = d.Timezones
+		records[i].IDD = d.IDD
+		records[i].Borders = d.Borders
+		records[i].AltSpellings = d.AltSpellings
+	}
+}
+
+type restCountryV3 struct {
+	Name struct {
+		Common   string `json:"common"`
+		Official string `json:"official"`
+	} `json:"name"`
+	CCA2       string   `json:"cca2"`
+	CCA3       string   `json:"cca3"`
+	CCN3       string   `json:"ccn3"`
+	Capital    []string `json:"capital"`
+	Region     string   `json:"region"`
+	Subregion  string   `json:"subregion"`
+	Population int64    `json:"population"`
+	Area       float64  `json:"area"`
+	Flags      struct {
+		PNG string `json:"png"`
+		SVG string `json:"svg"`
+	} `json:"flags"`
+	Currencies v3Currencies `json:"currencies"`
+	Languages  v3Languages  `json:"languages"`
+	Timezones  []string     `json:"timezones"`
+	IDD        struct {
+		Root     string   `json:"root"`
+		Suffixes []string `json:"suffixes"`
+	} `json:"idd"`
+	Borders      []string `json:"borders"`
+	AltSpellings []string `json:"altSpellings"`
+}
+
+func (r restCountryV3) toCountry() model.Country {
+	country := model.Country{
+		Name:         r.Name.Common,
+		Region:       r.Region,
+		Population:   r.Population,
+		FlagURL:      r.Flags.SVG,
+		Currencies:   r.Currencies,
+		OfficialName: r.Name.Official,
+		Alpha2Code:   r.CCA2,
+		Alpha3Code:   r.CCA3,
+		NumericCode:  r.CCN3,
+		Subregion:    r.Subregion,
+		Area:         r.Area,
+		Languages:    r.Languages,
+		Timezones:    r.Timezones,
+		Borders:      r.Borders,
+		AltSpellings: r.AltSpellings,
+	}
+	if len(r.Capital) > 0 {
+		country.Capital = r.Capital[0]
+	}
+	if country.FlagURL == "" {
+		country.FlagURL = r.Flags.PNG
+	}
+	country.CallingCodes = r.callingCodes()
+	return country
+}
+
+// callingCodes converts the v3.1 idd object to v2 calling codes. Like v2,
+// a root shared by many suffixes, such as +1, is given as the root alone.
+func (r restCountryV3) callingCodes() []string {
+	root := strings.TrimPrefix(r.IDD.Root, "+")
+	switch {
+	case root == "":
+		return nil
+	case len(r.IDD.Suffixes) == 1:
+		return []string{root + r.IDD.Suffixes[0]}
+	default:
+		return []string{root}
+	}
+}
+
+// v3Currencies decodes the v3.1 currencies object, keyed by code, keeping
+// the upstream order so the first currency stays the primary one.
+type v3Currencies []model.CountryCurrency
+
+func (c *v3Currencies) UnmarshalJSON(data []byte) error {
+	return eachObjectEntry(data, func(key string, value json.RawMessage) error {
+		var currency struct {
+			Name   string `json:"name"`
+			Symbol string `json:"symbol"`
+		}
+		if err := json.Unmarshal(value, &currency); err != nil {
+			return err
+		}
+		*c = append(*c, model.CountryCurrency{Code: key, Name: currency.Name, Symbol: currency.Symbol})
+		return nil
+	})
+}
+
+// v3Languages decodes the v3.1 languages object, which maps language codes
+// to names, in upstream order.
+type v3Languages []model.CountryLanguage
+
+func (l *v3Languages) UnmarshalJSON(data []byte) error {
+	return eachObjectEntry(data, func(key string, value json.RawMessage) error {
+		var name string
+		if err := json.Unmarshal(value, &name); err != nil {
+			return err
+		}
+		*l = append(*l, model.CountryLanguage{Code: key, Name: name})
+		return nil
+	})
+}
+
+// eachObjectEntry calls fn for every member of a JSON object in document
+// order. null and empty objects yield no calls.
+func eachObjectEntry(data []byte, fn func(key string, value json.RawMessage) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a JSON object, got %v", token)
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("expected an object key, got %v", token)
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
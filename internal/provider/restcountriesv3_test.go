@@ -0,0 +1,228 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/justinndidit/forex/internal/upstream"
+	"github.com/rs/zerolog"
+)
+
+func TestV3CurrenciesKeepUpstreamOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "upstream order, not alphabetical",
+			data: `{"GGP":{"name":"Guernsey pound","symbol":"£"},"GBP":{"name":"British pound","symbol":"£"}}`,
+			want: []string{"GGP", "GBP"},
+		},
+		{
+			name: "single currency",
+			data: `{"EUR":{"name":"Euro","symbol":"€"}}`,
+			want: []string{"EUR"},
+		},
+		{name: "empty object", data: `{}`},
+		{name: "null", data: `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var currencies v3Currencies
+			if err := json.Unmarshal([]byte(tt.data), &currencies); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			var codes []string
+			for _, c := range currencies {
+				codes = append(codes, c.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.want) {
+				t.Errorf("codes = %v, want %v", codes, tt.want)
+			}
+		})
+	}
+}
+
+func TestV3CurrencyNameAndSymbol(t *testing.T) {
+	var currencies v3Currencies
+	data := `{"NGN":{"name":"Nigerian naira","symbol":"₦"}}`
+	if err := json.Unmarshal([]byte(data), &currencies); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	want := v3Currencies{{Code: "NGN", Name: "Nigerian naira", Symbol: "₦"}}
+	if !reflect.DeepEqual(currencies, want) {
+		t.Errorf("currencies = %v, want %v", currencies, want)
+	}
+}
+
+func TestV3LanguagesKeepUpstreamOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "upstream order, not alphabetical",
+			data: `{"nld":"Dutch","fra":"French","deu":"German"}`,
+			want: []string{"nld:Dutch", "fra:French", "deu:German"},
+		},
+		{
+			name: "reverse alphabetical",
+			data: `{"eng":"English","bis":"Bislama"}`,
+			want: []string{"eng:English", "bis:Bislama"},
+		},
+		{name: "empty object", data: `{}`},
+		{name: "null", data: `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var languages v3Languages
+			if err := json.Unmarshal([]byte(tt.data), &languages); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			var got []string
+			for _, l := range languages {
+				got = append(got, l.Code+":"+l.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("languages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEachObjectEntryRejectsNonObjects(t *testing.T) {
+	for _, data := range []string{`[]`, `"EUR"`, `1`, `{"EUR":`} {
+		err := eachObjectEntry([]byte(data), func(string, json.RawMessage) error { return nil })
+		if err == nil {
+			t.Errorf("eachObjectEntry(%s) succeeded, want an error", data)
+		}
+	}
+}
+
+// detailsServer serves a main and a details request with ETags taken from
+// checksums of the current bodies, so changing a body makes its next request
+// return data again.
+type detailsServer struct {
+	main, details string
+}
+
+func (s *detailsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body := s.main
+	if r.URL.Path == "/details" {
+		body = s.details
+	}
+	etag := fmt.Sprintf(`"%08x"`, crc32.ChecksumIEEE([]byte(body)))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Write([]byte(body))
+}
+
+func TestRestCountriesV3FetchMergesDetails(t *testing.T) {
+	handler := &detailsServer{
+		main: `[
+			{"name":{"common":"Belgium","official":"Kingdom of Belgium"},"cca2":"BE","cca3":"BEL","population":11555997},
+			{"name":{"common":"Atlantis"},"cca3":"ATL","population":1}
+		]`,
+		details: `[
+			{"cca3":"BEL","ccn3":"056","languages":{"nld":"Dutch","fra":"French","deu":"German"},
+			 "timezones":["UTC+01:00"],"idd":{"root":"+3","suffixes":["2"]},"borders":["FRA","DEU"],"altSpellings":["BE"]}
+		]`,
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	source := &RestCountriesV3Source{
+		url:        server.URL + "/main",
+		detailsURL: server.URL + "/details",
+		client:     upstream.NewClient("test", upstream.Options{MaxRetries: -1}, &logger),
+	}
+
+	countries, err := source.FetchCountries(context.Background())
+	if err != nil {
+		t.Fatalf("FetchCountries: %v", err)
+	}
+	if len(countries) != 2 {
+		t.Fatalf("got %d countries, want 2", len(countries))
+	}
+
+	belgium := countries[0]
+	if belgium.NumericCode != "056" {
+		t.Errorf("NumericCode = %q, want 056", belgium.NumericCode)
+	}
+	if len(belgium.Languages) != 3 || belgium.Languages[0].Code != "nld" {
+		t.Errorf("Languages = %v, want nld, fra, deu", belgium.Languages)
+	}
+	if !reflect.DeepEqual(belgium.CallingCodes, []string{"32"}) {
+		t.Errorf("CallingCodes = %v, want [32]", belgium.CallingCodes)
+	}
+	if !reflect.DeepEqual(belgium.Borders, []string{"FRA", "DEU"}) {
+		t.Errorf("Borders = %v, want [FRA DEU]", belgium.Borders)
+	}
+	if !reflect.DeepEqual(belgium.Timezones, []string{"UTC+01:00"}) {
+		t.Errorf("Timezones = %v, want [UTC+01:00]", belgium.Timezones)
+	}
+
+	atlantis := countries[1]
+	if atlantis.Name != "Atlantis" || atlantis.NumericCode != "" || atlantis.Languages != nil {
+		t.Errorf("country without details = %+v, want the first request's fields only", atlantis)
+	}
+}
+
+func TestRestCountriesV3FetchNotModified(t *testing.T) {
+	handler := &detailsServer{
+		main:    `[{"name":{"common":"Belgium"},"cca3":"BEL","population":11555997}]`,
+		details: `[{"cca3":"BEL","ccn3":"056"}]`,
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	source := &RestCountriesV3Source{
+		url:        server.URL + "/main",
+		detailsURL: server.URL + "/details",
+		client:     upstream.NewClient("test", upstream.Options{CacheDir: t.TempDir(), MaxRetries: -1}, &logger),
+	}
+	ctx := context.Background()
+
+	if _, err := source.FetchCountries(ctx); err != nil {
+		t.Fatalf("first FetchCountries: %v", err)
+	}
+	if _, err := source.FetchCountries(ctx); !errors.Is(err, upstream.ErrNotModified) {
+		t.Fatalf("FetchCountries with nothing changed: err = %v, want ErrNotModified", err)
+	}
+
+	// Only the details changed: the first request's body comes from cache.
+	handler.details = `[{"cca3":"BEL","ccn3":"056","borders":["FRA"]}]`
+	countries, err := source.FetchCountries(ctx)
+	if err != nil {
+		t.Fatalf("FetchCountries with new details: %v", err)
+	}
+	if len(countries) != 1 || countries[0].Name != "Belgium" || !reflect.DeepEqual(countries[0].Borders, []string{"FRA"}) {
+		t.Errorf("countries = %+v, want Belgium with the new borders", countries)
+	}
+
+	// Only the first request changed: the details come from cache.
+	handler.main = `[{"name":{"common":"Belgium"},"cca3":"BEL","population":11600000}]`
+	countries, err = source.FetchCountries(ctx)
+	if err != nil {
+		t.Fatalf("FetchCountries with new countries: %v", err)
+	}
+	if len(countries) != 1 || countries[0].Population != 11600000 || countries[0].NumericCode != "056" {
+		t.Errorf("countries = %+v, want the new population with the cached details", countries)
+	}
+}
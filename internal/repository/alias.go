@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// saveUpstreamAliases records the alternative spellings of rows. An alias
+// that already exists is moved to its new country unless it was set by
+// hand. Aliases upstream stops sending are kept, and deleted ones stay
+// deleted.
+func (r *ForexRepository) saveUpstreamAliases(ctx context.Context, tx *sql.Tx, rows []model.CountryDBRow) error {
+	type aliasRow struct {
+		alias   string
+		country string
+	}
+	var aliases []aliasRow
+	seen := map[string]bool{}
+	for _, row := range rows {
+		for _, spelling := range row.AltSpellings {
+			alias := model.NormalizeCountryName(spelling)
+			if alias == "" || alias == row.Name || seen[alias] {
+				continue
+			}
+			seen[alias] = true
+			aliases = append(aliases, aliasRow{alias, row.Name})
+		}
+	}
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, batchSize)
+	for i := 0; i < len(aliases); i += batchSize {
+		end := min(i+batchSize, len(aliases))
+
+		placeholders = placeholders[:0]
+		args := make([]any, 0, (end-i)*3)
+		for _, a := range aliases[i:end] {
+			placeholders = append(placeholders, "(?, ?, ?)")
+			args = append(args, a.alias, a.country, model.AliasSourceUpstream)
+		}
+
+		stmt := fmt.Sprintf(`
+            INSERT INTO %s (alias, country_name, source)
+            VALUES %s
+            ON DUPLICATE KEY UPDATE
+                country_name = IF(source = '%s', VALUES(country_name), country_name)
+        `, countryAliasesTable, strings.Join(placeholders, ", "), model.AliasSourceUpstream)
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to save upstream country aliases")
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCountryByAlias returns the country an alias points at.
+func (r *ForexRepository) GetCountryByAlias(ctx context.Context, alias string) (*model.CountryDBRow, error) {
+	stmt := fmt.Sprintf("SELECT country_name FROM %s WHERE alias = ? AND source <> ?", countryAliasesTable)
+
+	var name string
+	err := r.db.Pool.QueryRowContext(ctx, stmt, model.NormalizeCountryName(alias), model.AliasSourceDeleted).Scan(&name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		r.logger.Error().Err(err).Msg("Failed to query country alias")
+		return nil, err
+	}
+	return r.getCountry(ctx, "name", name)
+}
+
+// GetCountryAliases lists aliases ordered by alias, only those of country
+// when it is not empty.
+func (r *ForexRepository) GetCountryAliases(ctx context.Context, country string) ([]model.CountryAlias, error) {
+	stmt := fmt.Sprintf(`
+        SELECT alias, country_name, source, created_at, updated_at
+        FROM %s
+        WHERE source <> ?
+    `, countryAliasesTable)
+	args := []any{model.AliasSourceDeleted}
+	if country != "" {
+		stmt += " AND country_name = ?"
+		args = append(args, country)
+	}
+	stmt += " ORDER BY alias"
+
+	rows, err := r.db.Pool.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query country aliases")
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := []model.CountryAlias{}
+	for rows.Next() {
+		var a model.CountryAlias
+		if err := rows.Scan(&a.Alias, &a.CountryName, &a.Source, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan country alias row")
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return aliases, nil
+}
+
+// SetCountryAlias points alias at country by hand, replacing any upstream
+// or deleted alias of the same spelling.
+func (r *ForexRepository) SetCountryAlias(ctx context.Context, alias, country string) (*model.CountryAlias, error) {
+	alias = model.NormalizeCountryName(alias)
+	stmt := fmt.Sprintf(`
+        INSERT INTO %s (alias, country_name, source)
+        VALUES (?, ?, ?)
+        ON DUPLICATE KEY UPDATE
+            country_name = VALUES(country_name),
+            source = VALUES(source)
+    `, countryAliasesTable)
+	if _, err := r.db.Pool.ExecContext(ctx, stmt, alias, country, model.AliasSourceManual); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to save country alias")
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+        SELECT alias, country_name, source, created_at, updated_at
+        FROM %s
+        WHERE alias = ?
+    `, countryAliasesTable)
+	var a model.CountryAlias
+	err := r.db.Pool.QueryRowContext(ctx, query, alias).Scan(&a.Alias, &a.CountryName, &a.Source, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to scan country alias row")
+		return nil, err
+	}
+	return &a, nil
+}
+
+// DeleteCountryAlias marks alias deleted rather than removing its row, so
+// the next refresh does not seed it again from upstream.
+func (r *ForexRepository) DeleteCountryAlias(ctx context.Context, alias string) error {
+	stmt := fmt.Sprintf("UPDATE %s SET source = ? WHERE alias = ? AND source <> ?", countryAliasesTable)
+
+	result, err := r.db.Pool.ExecContext(ctx, stmt, model.AliasSourceDeleted, model.NormalizeCountryName(alias), model.AliasSourceDeleted)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to delete country alias")
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to read affected rows")
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrNotFound
+	}
+	return nil
+}
+
+// GetCountryNameCandidates maps every active country name and every alias
+// of an active country to the country's name, for suggesting names close
+// to one that was not found.
+func (r *ForexRepository) GetCountryNameCandidates(ctx context.Context) (map[string]string, error) {
+	stmt := fmt.Sprintf(`
+        SELECT name, name FROM %[1]s WHERE status = ?
+        UNION ALL
+        SELECT a.alias, a.country_name
+        FROM %[2]s a
+        JOIN %[1]s c ON c.name = a.country_name
+        WHERE c.status = ? AND a.source <> ?
+    `, countriesTable, countryAliasesTable)
+
+	rows, err := r.db.Pool.QueryContext(ctx, stmt, model.CountryStatusActive, model.CountryStatusActive, model.AliasSourceDeleted)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query country name candidates")
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := map[string]string{}
+	for rows.Next() {
+		var spelling, name string
+		if err := rows.Scan(&spelling, &name); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan country name candidate")
+			return nil, err
+		}
+		candidates[spelling] = name
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// GetCountryCodeCandidates maps the code of the given kind of every active
+// country to the country's name, for suggesting countries whose code is
+// close to one that was not found.
+func (r *ForexRepository) GetCountryCodeCandidates(ctx context.Context, kind string) (map[string]string, error) {
+	column, ok := countryCodeColumns[kind]
+	if !ok {
+		return map[string]string{}, nil
+	}
+
+	stmt := fmt.Sprintf(`
+        SELECT %[1]s, name FROM %[2]s
+        WHERE status = ? AND %[1]s IS NOT NULL
+    `, column, countriesTable)
+
+	rows, err := r.db.Pool.QueryContext(ctx, stmt, model.CountryStatusActive)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query country code candidates")
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := map[string]string{}
+	for rows.Next() {
+		var code, name string
+		if err := rows.Scan(&code, &name); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan country code candidate")
+			return nil, err
+		}
+		candidates[code] = name
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return candidates, nil
+}
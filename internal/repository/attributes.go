@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// countryList is a child table holding an ordered list of values per
+// country. Its rows are (country_id, position, columns...).
+type countryList struct {
+	table   string
+	columns []string
+	// entries returns row's list, one value per column for each entry.
+	entries func(row *model.CountryDBRow) [][]string
+	// add appends an entry read back from the table to country.
+	add func(country *model.CountryDBRow, entry []string)
+}
+
+var countryLists = []countryList{
+	{
+		table:   countryLanguagesTable,
+		columns: []string{"language_code", "language_name"},
+		entries: func(row *model.CountryDBRow) [][]string {
+			entries := make([][]string, 0, len(row.Languages))
+			for _, language := range row.Languages {
+				if language.Code != "" {
+					entries = append(entries, []string{language.Code, language.Name})
+				}
+			}
+			return entries
+		},
+		add: func(country *model.CountryDBRow, entry []string) {
+			country.Languages = append(country.Languages, model.CountryLanguage{Code: entry[0], Name: entry[1]})
+		},
+	},
+	stringList(countryTimezonesTable, "timezone", func(c *model.CountryDBRow) *[]string { return &c.Timezones }),
+	stringList(countryCallingCodesTable, "calling_code", func(c *model.CountryDBRow) *[]string { return &c.CallingCodes }),
+	stringList(countryBordersTable, "border_code", func(c *model.CountryDBRow) *[]string { return &c.Borders }),
+}
+
+// stringList describes a child table with a single value column backed by
+// the []string field returned by field.
+func stringList(table, column string, field func(c *model.CountryDBRow) *[]string) countryList {
+	return countryList{
+		table:   table,
+		columns: []string{column},
+		entries: func(row *model.CountryDBRow) [][]string {
+			values := *field(row)
+			entries := make([][]string, 0, len(values))
+			for _, value := range values {
+				if value != "" {
+					entries = append(entries, []string{value})
+				}
+			}
+			return entries
+		},
+		add: func(country *model.CountryDBRow, entry []string) {
+			values := field(country)
+			*values = append(*values, entry[0])
+		},
+	}
+}
+
+// replaceCountryLists rewrites the languages, timezones, calling codes and
+// borders of every country in temp_countries. Like replaceCountryCurrencies
+// it must run after the merge so new countries have an id.
+func (r *ForexRepository) replaceCountryLists(ctx context.Context, tx *sql.Tx, rows []model.CountryDBRow) error {
+	idsSQL := fmt.Sprintf(`
+        SELECT c.id, c.name
+        FROM %s c
+        JOIN temp_countries t ON t.name = c.name
+    `, countriesTable)
+	idRows, err := tx.QueryContext(ctx, idsSQL)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query refreshed country ids")
+		return err
+	}
+	ids := make(map[string]int64, len(rows))
+	for idRows.Next() {
+		var (
+			id   int64
+			name string
+		)
+		if err := idRows.Scan(&id, &name); err != nil {
+			idRows.Close()
+			r.logger.Error().Err(err).Msg("Failed to scan country id row")
+			return err
+		}
+		ids[name] = id
+	}
+	idRows.Close()
+	if err := idRows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return err
+	}
+
+	for _, list := range countryLists {
+		deleteSQL := fmt.Sprintf(`
+            DELETE l FROM %s l
+            JOIN %s c ON c.id = l.country_id
+            JOIN temp_countries t ON t.name = c.name
+        `, list.table, countriesTable)
+		if _, err := tx.ExecContext(ctx, deleteSQL); err != nil {
+			r.logger.Error().Err(err).Str("table", list.table).Msg("Failed to clear refreshed country list")
+			return err
+		}
+
+		var values [][]any
+		for i := range rows {
+			id, ok := ids[rows[i].Name]
+			if !ok {
+				continue
+			}
+			for position, entry := range list.entries(&rows[i]) {
+				args := []any{id, position}
+				for _, value := range entry {
+					args = append(args, sql.NullString{String: value, Valid: value != ""})
+				}
+				values = append(values, args)
+			}
+		}
+
+		columns := append([]string{"country_id", "position"}, list.columns...)
+		err := r.insertBatches(ctx, tx, "INSERT", list.table, columns, len(values), func(i int) []any {
+			return values[i]
+		})
+		if err != nil {
+			r.logger.Error().Err(err).Str("table", list.table).Msg("Failed to insert country list")
+			return err
+		}
+	}
+	return nil
+}
+
+// loadCountryLists attaches the languages, timezones, calling codes and
+// borders to each country, with one query per list.
+func (r *ForexRepository) loadCountryLists(ctx context.Context, countries []model.CountryDBRow) error {
+	if len(countries) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*model.CountryDBRow, len(countries))
+	placeholders := make([]string, 0, len(countries))
+	args := make([]any, 0, len(countries))
+	for i := range countries {
+		byID[countries[i].ID] = &countries[i]
+		placeholders = append(placeholders, "?")
+		args = append(args, countries[i].ID)
+	}
+
+	for _, list := range countryLists {
+		query := fmt.Sprintf(`
+            SELECT country_id, %s
+            FROM %s
+            WHERE country_id IN (%s)
+            ORDER BY country_id, position
+        `, strings.Join(list.columns, ", "), list.table, strings.Join(placeholders, ","))
+
+		if err := r.loadCountryList(ctx, list, query, args, byID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ForexRepository) loadCountryList(ctx context.Context, list countryList, query string, args []any, byID map[int64]*model.CountryDBRow) error {
+	rows, err := r.db.Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error().Err(err).Str("table", list.table).Msg("Failed to query country list")
+		return err
+	}
+	defer rows.Close()
+
+	values := make([]sql.NullString, len(list.columns))
+	dest := make([]any, len(list.columns)+1)
+	for i := range values {
+		dest[i+1] = &values[i]
+	}
+
+	for rows.Next() {
+		var countryID int64
+		dest[0] = &countryID
+		if err := rows.Scan(dest...); err != nil {
+			r.logger.Error().Err(err).Str("table", list.table).Msg("Failed to scan country list row")
+			return err
+		}
+
+		country, ok := byID[countryID]
+		if !ok {
+			continue
+		}
+		entry := make([]string, len(values))
+		for i, value := range values {
+			entry[i] = value.String
+		}
+		list.add(country, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// GetDeletedCountries lists tombstones, most recently deleted first.
+func (r *ForexRepository) GetDeletedCountries(ctx context.Context) ([]model.DeletedCountry, error) {
+	stmt := fmt.Sprintf("SELECT name, deleted_at FROM %s ORDER BY deleted_at DESC, name ASC", deletedCountriesTable)
+
+	rows, err := r.db.Pool.QueryContext(ctx, stmt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query deleted countries")
+		return nil, err
+	}
+	defer rows.Close()
+
+	deleted := []model.DeletedCountry{}
+	for rows.Next() {
+		var d model.DeletedCountry
+		if err := rows.Scan(&d.Name, &d.DeletedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan deleted country row")
+			return nil, err
+		}
+		deleted = append(deleted, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// RestoreCountry removes the tombstone for name and puts back the row it
+// kept, undoing the delete. Tombstones without a kept row only let the
+// next refresh insert the country again. The restore is recorded in
+// app_status so that refresh downloads the country list even if upstream
+// reports it unchanged.
+func (r *ForexRepository) RestoreCountry(ctx context.Context, name string) error {
+	tx, err := r.db.Pool.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	var record sql.NullString
+	selectSQL := fmt.Sprintf("SELECT record FROM %s WHERE name = ? FOR UPDATE", deletedCountriesTable)
+	if err := tx.QueryRowContext(ctx, selectSQL, name).Scan(&record); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrNotFound
+		}
+		r.logger.Error().Err(err).Msg("Failed to read country tombstone")
+		return fmt.Errorf("failed to restore country: %w", err)
+	}
+
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE name = ?", deletedCountriesTable)
+	if _, err := tx.ExecContext(ctx, stmt, name); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to delete country tombstone")
+		return fmt.Errorf("failed to restore country: %w", err)
+	}
+
+	if record.Valid {
+		var country model.CountryDBRow
+		if err := json.Unmarshal([]byte(record.String), &country); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to decode deleted country")
+			return fmt.Errorf("failed to decode deleted country: %w", err)
+		}
+		if err := r.upsertCountries(ctx, tx, []model.CountryDBRow{country}); err != nil {
+			return fmt.Errorf("failed to restore country: %w", err)
+		}
+	}
+
+	statusSQL := fmt.Sprintf("UPDATE %s SET countries_restored_at = ? WHERE id = 1", appStatusTable)
+	if _, err := tx.ExecContext(ctx, statusSQL, time.Now()); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update app_status")
+		return fmt.Errorf("failed to restore country: %w", err)
+	}
+
+	return tx.Commit()
+}
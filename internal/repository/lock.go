@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// RefreshLockName is the MySQL named lock held for the duration of a
+// refresh, so refreshes never overlap across instances.
+const RefreshLockName = "forex_refresh"
+
+// NamedLock is a MySQL named lock. It belongs to the session of the
+// connection that took it, so that connection is kept out of the pool until
+// Release.
+type NamedLock struct {
+	repo *ForexRepository
+	conn *sql.Conn
+	name string
+}
+
+// TryLock takes the named lock without waiting. It returns a nil lock if
+// another session holds it.
+func (r *ForexRepository) TryLock(ctx context.Context, name string) (*NamedLock, error) {
+	conn, err := r.db.Pool.Conn(ctx)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get connection for lock")
+		return nil, err
+	}
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&acquired); err != nil {
+		conn.Close()
+		r.logger.Error().Err(err).Str("lock", name).Msg("Failed to take lock")
+		return nil, err
+	}
+	if !acquired.Valid {
+		conn.Close()
+		return nil, fmt.Errorf("failed to take lock %q", name)
+	}
+	if acquired.Int64 != 1 {
+		conn.Close()
+		return nil, nil
+	}
+
+	return &NamedLock{repo: r, conn: conn, name: name}, nil
+}
+
+// Release frees the lock. If the server cannot be told, the connection is
+// discarded instead: closing its session frees the lock too.
+func (l *NamedLock) Release(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "DO RELEASE_LOCK(?)", l.name)
+	if err != nil {
+		l.repo.logger.Error().Err(err).Str("lock", l.name).Msg("Failed to release lock, discarding connection")
+		l.conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	l.conn.Close()
+	return err
+}
+
+// LockHeld reports whether any session holds the named lock.
+func (r *ForexRepository) LockHeld(ctx context.Context, name string) (bool, error) {
+	var holder sql.NullInt64
+	if err := r.db.Pool.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?)", name).Scan(&holder); err != nil {
+		r.logger.Error().Err(err).Str("lock", name).Msg("Failed to check lock")
+		return false, err
+	}
+	return holder.Valid, nil
+}
+
+// GetRunningRefreshRun returns the most recently started run that has not
+// finished, or errs.ErrNotFound.
+func (r *ForexRepository) GetRunningRefreshRun(ctx context.Context) (*model.RefreshRun, error) {
+	stmt := fmt.Sprintf("SELECT %s FROM %s WHERE status = ? ORDER BY id DESC LIMIT 1", refreshRunColumns, refreshRunsTable)
+
+	run, err := scanRefreshRun(r.db.Pool.QueryRowContext(ctx, stmt, model.RefreshJobRunning))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		r.logger.Error().Err(err).Msg("Failed to scan refresh run row")
+		return nil, err
+	}
+	return run, nil
+}
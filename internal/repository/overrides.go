@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// countryColumn returns the SQL expression for a countries column as the
+// API sees it: the manual override if there is one, else the stored value.
+func (r *ForexRepository) countryColumn(column string, raw bool) string {
+	if raw {
+		return column
+	}
+
+	override := fmt.Sprintf(
+		"(SELECT o.value FROM %s o WHERE o.country_name = %s.name AND o.field = '%s')",
+		countryOverridesTable, countriesTable, column,
+	)
+	if column == "population" {
+		override = "CAST(" + override + " AS SIGNED)"
+	}
+	return fmt.Sprintf("COALESCE(%s, %s.%s)", override, countriesTable, column)
+}
+
+// notOverridden returns an SQL condition that holds when the API sees the
+// stored value of a countries column, with no manual override in the way.
+func (r *ForexRepository) notOverridden(column string, raw bool) string {
+	if raw {
+		return "TRUE"
+	}
+	return fmt.Sprintf(
+		"NOT EXISTS (SELECT 1 FROM %s o WHERE o.country_name = %s.name AND o.field = '%s')",
+		countryOverridesTable, countriesTable, column,
+	)
+}
+
+// SetCountryOverrides upserts the given field overrides of a country and
+// removes the overrides listed in remove, in one transaction.
+func (r *ForexRepository) SetCountryOverrides(ctx context.Context, name, author, reason string, set map[string]sql.NullString, remove []string) error {
+	tx, err := r.db.Pool.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	upsertSQL := fmt.Sprintf(`
+        INSERT INTO %s (country_name, field, value, author, reason)
+        VALUES (?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE
+            value = VALUES(value),
+            author = VALUES(author),
+            reason = VALUES(reason)
+    `, countryOverridesTable)
+	for field, value := range set {
+		if _, err := tx.ExecContext(ctx, upsertSQL, name, field, value, author, reason); err != nil {
+			r.logger.Error().Err(err).Str("field", field).Msg("Failed to upsert country override")
+			return fmt.Errorf("failed to save country override: %w", err)
+		}
+	}
+
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE country_name = ? AND field = ?", countryOverridesTable)
+	for _, field := range remove {
+		if _, err := tx.ExecContext(ctx, deleteSQL, name, field); err != nil {
+			r.logger.Error().Err(err).Str("field", field).Msg("Failed to delete country override")
+			return fmt.Errorf("failed to remove country override: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetCountryOverrides returns the overrides of one country, ordered by field.
+func (r *ForexRepository) GetCountryOverrides(ctx context.Context, name string) ([]model.CountryOverride, error) {
+	return r.queryOverrides(ctx, []string{name})
+}
+
+// loadOverrides applies stored overrides to each country with a single
+// query.
+func (r *ForexRepository) loadOverrides(ctx context.Context, countries []model.CountryDBRow) error {
+	if len(countries) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(countries))
+	for i := range countries {
+		names[i] = countries[i].Name
+	}
+
+	overrides, err := r.queryOverrides(ctx, names)
+	if err != nil {
+		return err
+	}
+
+	rates, err := r.overrideRates(ctx, overrides)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string][]model.CountryOverride, len(overrides))
+	for _, o := range overrides {
+		key := strings.ToLower(o.CountryName)
+		byName[key] = append(byName[key], o)
+	}
+	for i := range countries {
+		if o, ok := byName[strings.ToLower(countries[i].Name)]; ok {
+			countries[i].ApplyOverrides(o, rates)
+		}
+	}
+	return nil
+}
+
+// overrideRates returns the stored rate of every currency code set by a
+// currency_code override: the rate stored with the countries using it, else
+// the latest one in the rate history against the current base.
+func (r *ForexRepository) overrideRates(ctx context.Context, overrides []model.CountryOverride) (map[string]float64, error) {
+	seen := map[string]bool{}
+	var codes []any
+	for _, o := range overrides {
+		if o.Field == "currency_code" && o.Value.Valid && !seen[o.Value.String] {
+			seen[o.Value.String] = true
+			codes = append(codes, o.Value.String)
+		}
+	}
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(codes)), ",")
+
+	rates := make(map[string]float64, len(codes))
+	storedSQL := fmt.Sprintf(`
+        SELECT currency_code, MAX(exchange_rate)
+        FROM %s
+        WHERE currency_code IN (%s) AND exchange_rate > 0
+        GROUP BY currency_code
+    `, countryCurrenciesTable, placeholders)
+	if err := r.queryRates(ctx, rates, storedSQL, codes...); err != nil {
+		return nil, err
+	}
+	if len(rates) == len(codes) {
+		return rates, nil
+	}
+
+	baseCurrency, err := r.GetBaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	historySQL := fmt.Sprintf(`
+        SELECT h.currency_code, h.rate
+        FROM %[1]s h
+        JOIN (
+            SELECT currency_code, MAX(rate_timestamp) AS rate_timestamp
+            FROM %[1]s
+            WHERE base_code = ? AND currency_code IN (%[2]s)
+            GROUP BY currency_code
+        ) latest ON latest.currency_code = h.currency_code AND latest.rate_timestamp = h.rate_timestamp
+        WHERE h.base_code = ? AND h.rate > 0
+    `, rateHistoryTable, placeholders)
+	args := append(append([]any{baseCurrency}, codes...), baseCurrency)
+	history := map[string]float64{}
+	if err := r.queryRates(ctx, history, historySQL, args...); err != nil {
+		return nil, err
+	}
+	for code, rate := range history {
+		if _, ok := rates[code]; !ok {
+			rates[code] = rate
+		}
+	}
+	return rates, nil
+}
+
+// queryRates adds the (currency_code, rate) rows of query to rates.
+func (r *ForexRepository) queryRates(ctx context.Context, rates map[string]float64, query string, args ...any) error {
+	rows, err := r.db.Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query override exchange rates")
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			code string
+			rate float64
+		)
+		if err := rows.Scan(&code, &rate); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan override exchange rate row")
+			return err
+		}
+		rates[code] = rate
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return err
+	}
+	return nil
+}
+
+func (r *ForexRepository) queryOverrides(ctx context.Context, names []string) ([]model.CountryOverride, error) {
+	placeholders := make([]string, len(names))
+	args := make([]any, len(names))
+	for i, name := range names {
+		placeholders[i] = "?"
+		args[i] = name
+	}
+
+	query := fmt.Sprintf(`
+        SELECT country_name, field, value, author, reason, created_at, updated_at
+        FROM %s
+        WHERE country_name IN (%s)
+        ORDER BY country_name, field
+    `, countryOverridesTable, strings.Join(placeholders, ","))
+
+	rows, err := r.db.Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query country overrides")
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []model.CountryOverride{}
+	for rows.Next() {
+		var o model.CountryOverride
+		if err := rows.Scan(&o.CountryName, &o.Field, &o.Value, &o.Author, &o.Reason, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan country override row")
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// SaveUpstreamSources records the latest fetch from each provider in
+// sources, replacing what was stored for it.
+func (r *ForexRepository) SaveUpstreamSources(ctx context.Context, sources []model.UpstreamSource) error {
+	if len(sources) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Pool.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	columns := []string{"name", "kind", "url", "data_updated_at", "fetched_at"}
+	err = r.insertBatches(ctx, tx, "REPLACE", upstreamSourcesTable, columns, len(sources), func(i int) []any {
+		s := sources[i]
+		return []any{s.Name, s.Kind, s.URL, s.DataUpdatedAt, s.FetchedAt}
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to save upstream sources")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ForexRepository) GetUpstreamSources(ctx context.Context) ([]model.UpstreamSource, error) {
+	stmt := fmt.Sprintf(`
+        SELECT name, kind, url, data_updated_at, fetched_at
+        FROM %s
+        ORDER BY kind, name
+    `, upstreamSourcesTable)
+
+	rows, err := r.db.Pool.QueryContext(ctx, stmt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query upstream sources")
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []model.UpstreamSource{}
+	for rows.Next() {
+		var s model.UpstreamSource
+		if err := rows.Scan(&s.Name, &s.Kind, &s.URL, &s.DataUpdatedAt, &s.FetchedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan upstream source row")
+			return nil, err
+		}
+		sources = append(sources, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return sources, nil
+}
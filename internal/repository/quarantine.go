@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/justinndidit/forex/internal/model"
+)
+
+func (r *ForexRepository) SaveQuarantinedCountries(ctx context.Context, runID int64, quarantined []model.QuarantinedCountry) error {
+	if len(quarantined) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Pool.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	columns := []string{"run_id", "country_name", "currency_code", "rule", "reason", "record"}
+	err = r.insertBatches(ctx, tx, "INSERT", quarantinedCountriesTable, columns, len(quarantined), func(i int) []any {
+		q := quarantined[i]
+		return []any{runID, q.CountryName, q.Currency, q.Rule, q.Reason, q.Record}
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Int64("run_id", runID).Msg("Failed to insert quarantined countries")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ForexRepository) GetQuarantinedCountries(ctx context.Context, runID int64) ([]model.QuarantinedCountry, error) {
+	stmt := fmt.Sprintf(`
+        SELECT id, run_id, country_name, currency_code, rule, reason, record, quarantined_at
+        FROM %s
+        WHERE run_id = ?
+        ORDER BY country_name, id
+    `, quarantinedCountriesTable)
+
+	rows, err := r.db.Pool.QueryContext(ctx, stmt, runID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query quarantined countries")
+		return nil, err
+	}
+	defer rows.Close()
+
+	quarantined := []model.QuarantinedCountry{}
+	for rows.Next() {
+		var q model.QuarantinedCountry
+		if err := rows.Scan(&q.ID, &q.RunID, &q.CountryName, &q.Currency, &q.Rule, &q.Reason, &q.Record, &q.QuarantinedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan quarantined country row")
+			return nil, err
+		}
+		quarantined = append(quarantined, q)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return quarantined, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// AppendRateHistory records a snapshot of rates. Snapshots are keyed by
+// currency, base and upstream timestamp, so re-recording an upstream
+// snapshot we already have is a no-op. The provider of each rate is stored
+// alongside it, and each rate is stamped with its provider's update time;
+// rateTimestamp is used for rates whose provider did not report one.
+func (r *ForexRepository) AppendRateHistory(ctx context.Context, baseCode string, exchangeData *model.ExchangeRates, rateTimestamp time.Time) error {
+	rates := exchangeData.Rates
+	if len(rates) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(rates))
+	for code := range rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	tx, err := r.db.Pool.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	columns := []string{"currency_code", "base_code", "rate", "rate_timestamp", "rate_source"}
+	err = r.insertBatches(ctx, tx, "INSERT IGNORE", rateHistoryTable, columns, len(codes), func(i int) []any {
+		source := exchangeData.SourceOf(codes[i])
+		timestamp := rateTimestamp
+		if p, ok := exchangeData.ProvenanceOf(codes[i]); ok && !p.UpdatedAt.IsZero() {
+			timestamp = p.UpdatedAt
+		}
+		return []any{codes[i], baseCode, rates[codes[i]], timestamp, sql.NullString{String: source, Valid: source != ""}}
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to append exchange rate history")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetRateHistory returns the snapshots of a currency between from and to,
+// oldest first. Only snapshots against the most recently used base are
+// returned so a base change never mixes two series.
+func (r *ForexRepository) GetRateHistory(ctx context.Context, code string, from, to time.Time) ([]model.RateHistoryDBRow, error) {
+	query := fmt.Sprintf(`
+        SELECT currency_code, base_code, rate, rate_timestamp
+        FROM %[1]s
+        WHERE currency_code = ?
+          AND base_code = (
+              SELECT base_code FROM %[1]s
+              WHERE currency_code = ?
+              ORDER BY rate_timestamp DESC
+              LIMIT 1
+          )
+          AND rate_timestamp BETWEEN ? AND ?
+        ORDER BY rate_timestamp ASC
+    `, rateHistoryTable)
+
+	rows, err := r.db.Pool.QueryContext(ctx, query, code, code, from, to)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query exchange rate history")
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []model.RateHistoryDBRow{}
+	for rows.Next() {
+		var h model.RateHistoryDBRow
+		if err := rows.Scan(&h.CurrencyCode, &h.BaseCode, &h.Rate, &h.RateTimestamp); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan exchange rate history row")
+			return nil, err
+		}
+		history = append(history, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// PruneRateHistory deletes snapshots whose upstream timestamp is older than
+// the cutoff and returns how many were removed.
+func (r *ForexRepository) PruneRateHistory(ctx context.Context, cutoff time.Time) (int64, error) {
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE rate_timestamp < ?", rateHistoryTable)
+
+	result, err := r.db.Pool.ExecContext(ctx, stmt, cutoff)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to prune exchange rate history")
+		return 0, fmt.Errorf("failed to prune exchange rate history: %w", err)
+	}
+	return result.RowsAffected()
+}
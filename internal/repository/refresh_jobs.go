@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/model"
+)
+
+const refreshJobColumns = `
+            id, status, trigger_type, queued_at, started_at, finished_at,
+            duration_ms, countries_processed, countries_refreshed, rates_refreshed,
+            error_message, error_details
+`
+
+func (r *ForexRepository) CreateRefreshJob(ctx context.Context, trigger string) (*model.RefreshJob, error) {
+	queuedAt := time.Now()
+	stmt := fmt.Sprintf("INSERT INTO %s (status, trigger_type, queued_at) VALUES (?, ?, ?)", refreshJobsTable)
+
+	result, err := r.db.Pool.ExecContext(ctx, stmt, model.RefreshJobQueued, trigger, queuedAt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to insert refresh job")
+		return nil, fmt.Errorf("failed to create refresh job: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get refresh job id")
+		return nil, fmt.Errorf("failed to get refresh job id: %w", err)
+	}
+
+	return &model.RefreshJob{
+		ID:       id,
+		Status:   model.RefreshJobQueued,
+		Trigger:  trigger,
+		QueuedAt: queuedAt,
+	}, nil
+}
+
+// ClaimRefreshJob moves a queued job to running. It reports false if the job
+// is no longer queued, i.e. another worker claimed or finished it first.
+func (r *ForexRepository) ClaimRefreshJob(ctx context.Context, id int64, startedAt time.Time) (bool, error) {
+	stmt := fmt.Sprintf("UPDATE %s SET status = ?, started_at = ? WHERE id = ? AND status = ?", refreshJobsTable)
+
+	result, err := r.db.Pool.ExecContext(ctx, stmt, model.RefreshJobRunning, startedAt, id, model.RefreshJobQueued)
+	if err != nil {
+		r.logger.Error().Err(err).Int64("job_id", id).Msg("Failed to claim refresh job")
+		return false, fmt.Errorf("failed to update refresh job: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Int64("job_id", id).Msg("Failed to get affected rows")
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// FinishRefreshJob stores job's outcome if the job is still in status from.
+// It returns errs.ErrNotFound otherwise, so a job is never finished twice.
+func (r *ForexRepository) FinishRefreshJob(ctx context.Context, job *model.RefreshJob, from string) error {
+	stmt := fmt.Sprintf(`
+        UPDATE %s SET
+            status = ?, finished_at = ?, duration_ms = ?,
+            countries_processed = ?, countries_refreshed = ?, rates_refreshed = ?,
+            error_message = ?, error_details = ?
+        WHERE id = ? AND status = ?
+    `, refreshJobsTable)
+
+	result, err := r.db.Pool.ExecContext(ctx, stmt,
+		job.Status, job.FinishedAt, job.DurationMs,
+		job.CountriesProcessed, job.CountriesRefreshed, job.RatesRefreshed,
+		job.ErrorMessage, job.ErrorDetails,
+		job.ID, from,
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Int64("job_id", job.ID).Msg("Failed to finish refresh job")
+		return fmt.Errorf("failed to update refresh job: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Int64("job_id", job.ID).Msg("Failed to get affected rows")
+		return err
+	}
+	if affected == 0 {
+		return errs.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ForexRepository) GetRefreshJob(ctx context.Context, id int64) (*model.RefreshJob, error) {
+	stmt := fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", refreshJobColumns, refreshJobsTable)
+
+	row := r.db.Pool.QueryRowContext(ctx, stmt, id)
+
+	job, err := scanRefreshJob(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		r.logger.Error().Err(err).Msg("Failed to scan refresh job row")
+		return nil, err
+	}
+	return job, nil
+}
+
+// FailInterruptedRefreshJobs marks jobs left running by a process that
+// stopped mid-refresh as failed; their outcome is unknown. Jobs are claimed
+// and finished under the refresh lock, so callers holding it never fail a
+// live job.
+func (r *ForexRepository) FailInterruptedRefreshJobs(ctx context.Context, finishedAt time.Time) (int64, error) {
+	stmt := fmt.Sprintf(`
+        UPDATE %s SET
+            status = ?, finished_at = ?,
+            error_message = 'Refresh interrupted',
+            error_details = 'the service stopped before this job completed'
+        WHERE status = ?
+    `, refreshJobsTable)
+
+	result, err := r.db.Pool.ExecContext(ctx, stmt, model.RefreshJobFailed, finishedAt, model.RefreshJobRunning)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to fail interrupted refresh jobs")
+		return 0, fmt.Errorf("failed to update interrupted refresh jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *ForexRepository) GetQueuedRefreshJobIDs(ctx context.Context) ([]int64, error) {
+	stmt := fmt.Sprintf("SELECT id FROM %s WHERE status = ? ORDER BY id ASC", refreshJobsTable)
+
+	rows, err := r.db.Pool.QueryContext(ctx, stmt, model.RefreshJobQueued)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query queued refresh jobs")
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan refresh job id")
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func scanRefreshJob(row *sql.Row) (*model.RefreshJob, error) {
+	var j model.RefreshJob
+	err := row.Scan(
+		&j.ID, &j.Status, &j.Trigger, &j.QueuedAt, &j.StartedAt, &j.FinishedAt,
+		&j.DurationMs, &j.CountriesProcessed, &j.CountriesRefreshed, &j.RatesRefreshed,
+		&j.ErrorMessage, &j.ErrorDetails,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
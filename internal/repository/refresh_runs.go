@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/model"
+)
+
+const refreshRunColumns = `
+            id, job_id, trigger_type, status, started_at, finished_at, duration_ms,
+            country_source, rate_source, countries_latency_ms, rates_latency_ms,
+            countries_not_modified, rates_not_modified, countries_refreshed, rates_refreshed,
+            countries_inserted, countries_updated, countries_unchanged, countries_removed,
+            countries_quarantined, error_message, error_details
+`
+
+// CreateRefreshRun inserts run as running and sets its ID.
+func (r *ForexRepository) CreateRefreshRun(ctx context.Context, run *model.RefreshRun) error {
+	stmt := fmt.Sprintf(`
+        INSERT INTO %s (job_id, trigger_type, status, started_at, country_source, rate_source)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, refreshRunsTable)
+
+	result, err := r.db.Pool.ExecContext(ctx, stmt,
+		run.JobID, run.Trigger, run.Status, run.StartedAt, run.CountrySource, run.RateSource,
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to insert refresh run")
+		return fmt.Errorf("failed to create refresh run: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get refresh run id")
+		return fmt.Errorf("failed to get refresh run id: %w", err)
+	}
+	run.ID = id
+	return nil
+}
+
+func (r *ForexRepository) FinishRefreshRun(ctx context.Context, run *model.RefreshRun) error {
+	stmt := fmt.Sprintf(`
+        UPDATE %s SET
+            status = ?, finished_at = ?, duration_ms = ?,
+            countries_latency_ms = ?, rates_latency_ms = ?,
+            countries_not_modified = ?, rates_not_modified = ?,
+            countries_refreshed = ?, rates_refreshed = ?,
+            countries_inserted = ?, countries_updated = ?, countries_unchanged = ?,
+            countries_removed = ?, countries_quarantined = ?,
+            error_message = ?, error_details = ?
+        WHERE id = ?
+    `, refreshRunsTable)
+
+	_, err := r.db.Pool.ExecContext(ctx, stmt,
+		run.Status, run.FinishedAt, run.DurationMs,
+		run.CountriesLatencyMs, run.RatesLatencyMs,
+		run.CountriesNotModified, run.RatesNotModified,
+		run.CountriesRefreshed, run.RatesRefreshed,
+		run.CountriesInserted, run.CountriesUpdated, run.CountriesUnchanged,
+		run.CountriesRemoved, run.CountriesQuarantined,
+		run.ErrorMessage, run.ErrorDetails,
+		run.ID,
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Int64("run_id", run.ID).Msg("Failed to finish refresh run")
+		return fmt.Errorf("failed to update refresh run: %w", err)
+	}
+	return nil
+}
+
+// FailInterruptedRefreshRuns marks runs left running by a process that
+// stopped or lost track of them as failed. Callers must hold the refresh
+// lock, so no run is still in progress.
+func (r *ForexRepository) FailInterruptedRefreshRuns(ctx context.Context, finishedAt time.Time) (int64, error) {
+	stmt := fmt.Sprintf(`
+        UPDATE %s SET
+            status = ?, finished_at = ?,
+            error_message = 'Refresh interrupted',
+            error_details = 'the refresh stopped before this run was recorded as finished'
+        WHERE status = ?
+    `, refreshRunsTable)
+
+	result, err := r.db.Pool.ExecContext(ctx, stmt, model.RefreshJobFailed, finishedAt, model.RefreshJobRunning)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to fail interrupted refresh runs")
+		return 0, fmt.Errorf("failed to update interrupted refresh runs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *ForexRepository) GetRefreshRun(ctx context.Context, id int64) (*model.RefreshRun, error) {
+	stmt := fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", refreshRunColumns, refreshRunsTable)
+
+	row := r.db.Pool.QueryRowContext(ctx, stmt, id)
+
+	run, err := scanRefreshRun(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		r.logger.Error().Err(err).Msg("Failed to scan refresh run row")
+		return nil, err
+	}
+	return run, nil
+}
+
+// ListRefreshRuns returns one page of runs, newest first, and the total
+// number of runs.
+func (r *ForexRepository) ListRefreshRuns(ctx context.Context, limit, offset int) ([]model.RefreshRun, int64, error) {
+	var total int64
+	countStmt := fmt.Sprintf("SELECT COUNT(*) FROM %s", refreshRunsTable)
+	if err := r.db.Pool.QueryRowContext(ctx, countStmt).Scan(&total); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to count refresh runs")
+		return nil, 0, err
+	}
+
+	stmt := fmt.Sprintf(`
+        SELECT %s FROM %s
+        ORDER BY id DESC
+        LIMIT ? OFFSET ?
+    `, refreshRunColumns, refreshRunsTable)
+
+	rows, err := r.db.Pool.QueryContext(ctx, stmt, limit, offset)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query refresh runs")
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	runs := []model.RefreshRun{}
+	for rows.Next() {
+		run, err := scanRefreshRun(rows)
+		if err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan refresh run row")
+			return nil, 0, err
+		}
+		runs = append(runs, *run)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, 0, err
+	}
+
+	return runs, total, nil
+}
+
+func scanRefreshRun(row rowScanner) (*model.RefreshRun, error) {
+	var run model.RefreshRun
+	err := row.Scan(
+		&run.ID, &run.JobID, &run.Trigger, &run.Status, &run.StartedAt, &run.FinishedAt, &run.DurationMs,
+		&run.CountrySource, &run.RateSource, &run.CountriesLatencyMs, &run.RatesLatencyMs,
+		&run.CountriesNotModified, &run.RatesNotModified, &run.CountriesRefreshed, &run.RatesRefreshed,
+		&run.CountriesInserted, &run.CountriesUpdated, &run.CountriesUnchanged, &run.CountriesRemoved,
+		&run.CountriesQuarantined, &run.ErrorMessage, &run.ErrorDetails,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// SaveRefreshRunChanges stores the diff computed for a run.
+func (r *ForexRepository) SaveRefreshRunChanges(ctx context.Context, runID int64, changes []model.CountryChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Pool.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	columns := []string{"run_id", "country_name", "change_type", "field", "old_value", "new_value", "delta"}
+	err = r.insertBatches(ctx, tx, "INSERT", runChangesTable, columns, len(changes), func(i int) []any {
+		c := changes[i]
+		return []any{runID, c.CountryName, c.ChangeType, c.Field, c.OldValue, c.NewValue, c.Delta}
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Int64("run_id", runID).Msg("Failed to insert refresh run changes")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetRefreshRunChanges returns the diff of a run ordered by country, with
+// each country's fields in the order they were recorded.
+func (r *ForexRepository) GetRefreshRunChanges(ctx context.Context, runID int64) ([]model.CountryChange, error) {
+	stmt := fmt.Sprintf(`
+        SELECT country_name, change_type, field, old_value, new_value, delta
+        FROM %s
+        WHERE run_id = ?
+        ORDER BY country_name, id
+    `, runChangesTable)
+
+	rows, err := r.db.Pool.QueryContext(ctx, stmt, runID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query refresh run changes")
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []model.CountryChange{}
+	for rows.Next() {
+		var c model.CountryChange
+		if err := rows.Scan(&c.CountryName, &c.ChangeType, &c.Field, &c.OldValue, &c.NewValue, &c.Delta); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan refresh run change row")
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return changes, nil
+}
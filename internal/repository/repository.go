@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql" // Import standard sql
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,11 +18,55 @@ import (
 
 // Define constants for table names
 const (
-	countriesTable = "countries"
-	appStatusTable = "app_status"
-	batchSize      = 1000 // Standard batch size for bulk inserts
+	countriesTable            = "countries"
+	appStatusTable            = "app_status"
+	refreshJobsTable          = "refresh_jobs"
+	countryCurrenciesTable    = "country_currencies"
+	rateHistoryTable          = "exchange_rate_history"
+	refreshRunsTable          = "refresh_runs"
+	runChangesTable           = "refresh_run_changes"
+	deletedCountriesTable     = "deleted_countries"
+	countryOverridesTable     = "country_overrides"
+	quarantinedCountriesTable = "quarantined_countries"
+	upstreamSourcesTable      = "upstream_sources"
+	countryLanguagesTable     = "country_languages"
+	countryTimezonesTable     = "country_timezones"
+	countryCallingCodesTable  = "country_calling_codes"
+	countryBordersTable       = "country_borders"
+	countryAliasesTable       = "country_aliases"
+	batchSize                 = 1000 // Standard batch size for bulk inserts
 )
 
+// countryUpsertColumns are written by UpdateCountries, in temp table order.
+// name must stay first: it is the upsert key and is never updated.
+var countryUpsertColumns = []string{
+	"name", "capital", "region", "population",
+	"currency_code", "exchange_rate", "estimated_gdp",
+	"flag_url", "last_refreshed_at", "rate_source", "rates_stale",
+	"gdp_method", "status", "inactive_since",
+	"country_source", "country_source_url", "countries_fetched_at",
+	"rate_source_url", "rates_updated_at", "rates_fetched_at",
+	"alpha2_code", "alpha3_code", "subregion", "area", "numeric_code",
+}
+
+// countryCodeColumns maps each kind of ISO 3166-1 code to its column.
+var countryCodeColumns = map[string]string{
+	model.CountryCodeAlpha2:  "alpha2_code",
+	model.CountryCodeAlpha3:  "alpha3_code",
+	model.CountryCodeNumeric: "numeric_code",
+}
+
+// countrySelectColumns matches the scan order of scanCountry.
+const countrySelectColumns = `
+            id, name, capital, region, population,
+            currency_code, exchange_rate, estimated_gdp,
+            flag_url, last_refreshed_at, rate_source, rates_stale,
+            gdp_method, status, inactive_since,
+            country_source, country_source_url, countries_fetched_at,
+            rate_source_url, rates_updated_at, rates_fetched_at,
+            alpha2_code, alpha3_code, subregion, area, numeric_code
+`
+
 type ForexRepository struct {
 	logger *zerolog.Logger
 	db     *database.Database
@@ -33,7 +79,11 @@ func NewForexRepository(logger *zerolog.Logger, db *database.Database) *ForexRep
 	}
 }
 
-func (r *ForexRepository) UpdateCountries(ctx context.Context, rowsToInsert []model.CountryDBRow, refreshTime time.Time) error {
+// UpdateCountries upserts rows and records in app_status which parts of the
+// data were refreshed at refreshTime. When the country list was refreshed,
+// stored countries missing from rows are handled according to reconcile.
+// baseCurrency is the currency the rows' rates are quoted against.
+func (r *ForexRepository) UpdateCountries(ctx context.Context, rowsToInsert []model.CountryDBRow, refreshTime time.Time, parts model.RefreshedParts, reconcile, baseCurrency string) error {
 	tx, err := r.db.Pool.BeginTx(ctx, nil)
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Failed to begin transaction")
@@ -41,8 +91,30 @@ func (r *ForexRepository) UpdateCountries(ctx context.Context, rowsToInsert []mo
 	}
 	defer tx.Rollback()
 
+	if err = r.upsertCountries(ctx, tx, rowsToInsert); err != nil {
+		return err
+	}
+
+	if parts.Countries {
+		if err = r.reconcileMissingCountries(ctx, tx, reconcile, refreshTime); err != nil {
+			return err
+		}
+	}
+
+	if err = r.updateAppStatus(ctx, tx, refreshTime, parts, baseCurrency); err != nil {
+		return err
+	}
+
+	// If all commands succeeded, commit the transaction
+	return tx.Commit()
+}
+
+// upsertCountries inserts or updates rowsToInsert, with their currencies and
+// lists, leaving them in temp_countries. Countries with a tombstone are
+// skipped.
+func (r *ForexRepository) upsertCountries(ctx context.Context, tx *sql.Tx, rowsToInsert []model.CountryDBRow) error {
 	dropTempTableSQL := `DROP TEMPORARY TABLE IF EXISTS temp_countries;`
-	if _, err = tx.ExecContext(ctx, dropTempTableSQL); err != nil {
+	if _, err := tx.ExecContext(ctx, dropTempTableSQL); err != nil {
 		r.logger.Error().Err(err).Msg("Failed to drop old temporary table")
 		return err
 	}
@@ -57,10 +129,26 @@ func (r *ForexRepository) UpdateCountries(ctx context.Context, rowsToInsert []mo
             exchange_rate DECIMAL(15, 6),
             estimated_gdp DECIMAL(20, 2),
             flag_url VARCHAR(256),
-            last_refreshed_at TIMESTAMP NOT NULL
+            last_refreshed_at TIMESTAMP NOT NULL,
+            rate_source VARCHAR(64),
+            rates_stale BOOLEAN NOT NULL,
+            gdp_method VARCHAR(16),
+            status VARCHAR(16) NOT NULL,
+            inactive_since TIMESTAMP NULL,
+            country_source VARCHAR(64),
+            country_source_url VARCHAR(512),
+            countries_fetched_at TIMESTAMP NULL,
+            rate_source_url VARCHAR(512),
+            rates_updated_at TIMESTAMP NULL,
+            rates_fetched_at TIMESTAMP NULL,
+            alpha2_code CHAR(2),
+            alpha3_code CHAR(3),
+            subregion VARCHAR(256),
+            area DECIMAL(15, 2),
+            numeric_code CHAR(3)
         ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
     `
-	if _, err = tx.ExecContext(ctx, createTempTableSQL); err != nil {
+	if _, err := tx.ExecContext(ctx, createTempTableSQL); err != nil {
 		r.logger.Error().Err(err).Msg("Failed to create temporary table")
 		return err
 	}
@@ -68,97 +156,147 @@ func (r *ForexRepository) UpdateCountries(ctx context.Context, rowsToInsert []mo
 	if len(rowsToInsert) == 0 {
 		r.logger.Info().Msg("No countries to update, skipping bulk insert.")
 	} else {
-		stmtSQL := `
-            INSERT INTO temp_countries (
-                name, capital, region, population,
-                currency_code, exchange_rate, estimated_gdp,
-                flag_url, last_refreshed_at
-            ) VALUES %s
-        `
-
-		for i := 0; i < len(rowsToInsert); i += batchSize {
-			end := i + batchSize
-			if end > len(rowsToInsert) {
-				end = len(rowsToInsert)
-			}
-			batch := rowsToInsert[i:end]
-
-			valueStrings := make([]string, 0, len(batch))
-			valueArgs := make([]any, 0, len(batch)*9)
-
-			for _, row := range batch {
-				valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?)")
-				valueArgs = append(valueArgs,
-					row.Name, row.Capital, row.Region, row.Population,
-					row.CurrencyCode, row.ExchangeRate, row.EstimatedGDP,
-					row.FlagURL, row.LastRefreshedAt,
-				)
+		err := r.insertBatches(ctx, tx, "INSERT", "temp_countries", countryUpsertColumns, len(rowsToInsert), func(i int) []any {
+			row := rowsToInsert[i]
+			return []any{
+				row.Name, row.Capital, row.Region, row.Population,
+				row.CurrencyCode, row.ExchangeRate, row.EstimatedGDP,
+				row.FlagURL, row.LastRefreshedAt, row.RateSource, row.RatesStale,
+				row.GDPMethod, row.Status, row.InactiveSince,
+				row.CountrySource, row.CountrySourceURL, row.CountriesFetchedAt,
+				row.RateSourceURL, row.RatesUpdatedAt, row.RatesFetchedAt,
+				row.Alpha2Code, row.Alpha3Code, row.Subregion, row.Area, row.NumericCode,
 			}
+		})
+		if err != nil {
+			r.logger.Error().Err(err).Msg("Failed to bulk insert batch to temp table")
+			return err
+		}
+	}
+	// --- End of Batch Insert ---
 
-			batchStmt := fmt.Sprintf(stmtSQL, strings.Join(valueStrings, ","))
+	// Countries deleted by hand stay deleted.
+	dropDeletedSQL := fmt.Sprintf(`
+        DELETE t FROM temp_countries t
+        JOIN %s d ON d.name = t.name
+    `, deletedCountriesTable)
+	if _, err := tx.ExecContext(ctx, dropDeletedSQL); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to drop deleted countries from temp table")
+		return err
+	}
 
-			if _, err = tx.ExecContext(ctx, batchStmt, valueArgs...); err != nil {
-				r.logger.Error().Err(err).Msg("Failed to bulk insert batch to temp table")
-				return err
-			}
+	// ISO codes are unique. A code that moved to another country, say after
+	// a rename, is taken from the stored row that held it.
+	for _, column := range countryCodeColumns {
+		releaseSQL := fmt.Sprintf(`
+            UPDATE %[1]s c
+            JOIN temp_countries t ON t.%[2]s = c.%[2]s AND t.name <> c.name
+            SET c.%[2]s = NULL
+        `, countriesTable, column)
+		if _, err := tx.ExecContext(ctx, releaseSQL); err != nil {
+			r.logger.Error().Err(err).Str("column", column).Msg("Failed to release reassigned country codes")
+			return err
 		}
 	}
-	// --- End of Batch Insert ---
 
 	// --- MySQL "UPSERT" syntax ---
-	mergeSQL := `
-        INSERT INTO countries (
-            name, capital, region, population,
-            currency_code, exchange_rate, estimated_gdp,
-            flag_url, last_refreshed_at
-        )
-        SELECT * FROM temp_countries
+	updates := make([]string, 0, len(countryUpsertColumns)-1)
+	for _, column := range countryUpsertColumns[1:] {
+		updates = append(updates, fmt.Sprintf("%[1]s = VALUES(%[1]s)", column))
+	}
+	columnList := strings.Join(countryUpsertColumns, ", ")
+	mergeSQL := fmt.Sprintf(`
+        INSERT INTO %s (%s)
+        SELECT %s FROM temp_countries
         ON DUPLICATE KEY UPDATE
-            capital = VALUES(capital),
-            region = VALUES(region),
-            population = VALUES(population),
-            currency_code = VALUES(currency_code),
-            exchange_rate = VALUES(exchange_rate),
-            estimated_gdp = VALUES(estimated_gdp),
-            flag_url = VALUES(flag_url),
-            last_refreshed_at = VALUES(last_refreshed_at);
-    `
-	if _, err = tx.ExecContext(ctx, mergeSQL); err != nil {
+            %s;
+    `, countriesTable, columnList, columnList, strings.Join(updates, ",\n            "))
+	if _, err := tx.ExecContext(ctx, mergeSQL); err != nil {
 		r.logger.Error().Err(err).Msg("Failed to merge from temp table")
 		return err
 	}
 
-	// Use ? for placeholder
-	updateStatusSQL := fmt.Sprintf("UPDATE %s SET last_refreshed_at = ? WHERE id = 1", appStatusTable)
-	if _, err = tx.ExecContext(ctx, updateStatusSQL, refreshTime); err != nil {
-		r.logger.Error().Err(err).Msg("Failed to update app_status")
+	if err := r.replaceCountryCurrencies(ctx, tx, rowsToInsert); err != nil {
 		return err
 	}
+	if err := r.replaceCountryLists(ctx, tx, rowsToInsert); err != nil {
+		return err
+	}
+	return r.saveUpstreamAliases(ctx, tx, rowsToInsert)
+}
 
-	// If all commands succeeded, commit the transaction
+// MarkRefreshed records a refresh that found nothing new upstream: the stored
+// rows are already current, so only app_status moves forward.
+func (r *ForexRepository) MarkRefreshed(ctx context.Context, refreshTime time.Time, parts model.RefreshedParts) error {
+	tx, err := r.db.Pool.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = r.updateAppStatus(ctx, tx, refreshTime, parts, ""); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
+// updateAppStatus records a refresh in app_status. An empty baseCurrency
+// leaves the stored one as it is.
+func (r *ForexRepository) updateAppStatus(ctx context.Context, tx *sql.Tx, refreshTime time.Time, parts model.RefreshedParts, baseCurrency string) error {
+	// Use ? for placeholder
+	updateStatusSQL := fmt.Sprintf(`
+        UPDATE %s SET
+            last_refreshed_at = ?,
+            countries_refreshed_at = COALESCE(?, countries_refreshed_at),
+            rates_refreshed_at = COALESCE(?, rates_refreshed_at),
+            rates_stale = ?,
+            base_currency = COALESCE(?, base_currency)
+        WHERE id = 1
+    `, appStatusTable)
+	_, err := tx.ExecContext(ctx, updateStatusSQL,
+		refreshTime,
+		sql.NullTime{Time: refreshTime, Valid: parts.Countries},
+		sql.NullTime{Time: refreshTime, Valid: parts.Rates},
+		!parts.Rates,
+		sql.NullString{String: baseCurrency, Valid: baseCurrency != ""},
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update app_status")
+		return err
+	}
+	return nil
+}
+
 func (r *ForexRepository) GetCountries(ctx context.Context, filters model.CountryFilters) ([]model.CountryDBRow, error) {
 	// Use constants for table names and be explicit with columns
 	baseQuery := fmt.Sprintf(`
-        SELECT
-            id, name, capital, region, population, currency_code,
-            exchange_rate, estimated_gdp, flag_url, last_refreshed_at
+        SELECT %s
         FROM %s
-    `, countriesTable)
+    `, countrySelectColumns, countriesTable)
 
 	whereClauses := []string{}
 	args := []any{}
 
+	if !filters.IncludeInactive {
+		whereClauses = append(whereClauses, "status = ?")
+		args = append(args, model.CountryStatusActive)
+	}
+
 	if filters.Region != nil {
-		whereClauses = append(whereClauses, "region = ?")
+		whereClauses = append(whereClauses, r.countryColumn("region", filters.Raw)+" = ?")
 		args = append(args, *filters.Region)
 	}
 
 	if filters.Currency != nil {
-		whereClauses = append(whereClauses, "currency_code = ?")
-		args = append(args, *filters.Currency)
+		// Match any currency the country uses, not only the primary one.
+		// A country whose currency was overridden matches only the override.
+		whereClauses = append(whereClauses, fmt.Sprintf(
+			"(%s = ? OR (%s AND EXISTS (SELECT 1 FROM %s cc WHERE cc.country_id = %s.id AND cc.currency_code = ?)))",
+			r.countryColumn("currency_code", filters.Raw), r.notOverridden("currency_code", filters.Raw),
+			countryCurrenciesTable, countriesTable,
+		))
+		args = append(args, *filters.Currency, *filters.Currency)
 	}
 
 	finalQuery := baseQuery
@@ -176,9 +314,9 @@ func (r *ForexRepository) GetCountries(ctx context.Context, filters model.Countr
 		// FIX: Use MySQL syntax for NULLS FIRST
 		orderByClause = " ORDER BY estimated_gdp IS NULL DESC, estimated_gdp ASC"
 	case "population_desc":
-		orderByClause = " ORDER BY population DESC"
+		orderByClause = " ORDER BY " + r.countryColumn("population", filters.Raw) + " DESC"
 	case "population_asc":
-		orderByClause = " ORDER BY population ASC"
+		orderByClause = " ORDER BY " + r.countryColumn("population", filters.Raw) + " ASC"
 	case "name_desc":
 		orderByClause = " ORDER BY name DESC"
 	default:
@@ -194,27 +332,94 @@ func (r *ForexRepository) GetCountries(ctx context.Context, filters model.Countr
 	defer rows.Close()
 
 	// Use the helper to scan rows
-	return r.scanCountries(rows)
+	countries, err := r.scanCountries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadCurrencies(ctx, countries); err != nil {
+		return nil, err
+	}
+	if err := r.loadCountryLists(ctx, countries); err != nil {
+		return nil, err
+	}
+	if !filters.Raw {
+		if err := r.loadOverrides(ctx, countries); err != nil {
+			return nil, err
+		}
+		// Overrides can move the GDP estimate, so the SQL order is only a
+		// starting point.
+		switch filters.SortKey {
+		case "gdp_desc":
+			sortByGDP(countries, true)
+		case "gdp_asc":
+			sortByGDP(countries, false)
+		}
+	}
+	return countries, nil
 }
 
+// sortByGDP orders countries by estimated GDP, keeping countries without an
+// estimate last when descending and first when ascending, like the SQL
+// ordering.
+func sortByGDP(countries []model.CountryDBRow, desc bool) {
+	sort.SliceStable(countries, func(i, j int) bool {
+		a, b := countries[i].EstimatedGDP, countries[j].EstimatedGDP
+		if a.Valid != b.Valid {
+			return a.Valid == desc
+		}
+		if desc {
+			return a.Float64 > b.Float64
+		}
+		return a.Float64 < b.Float64
+	})
+}
+
+// GetCountryByName looks a country up by its stored name, then by alias.
 func (r *ForexRepository) GetCountryByName(ctx context.Context, name string) (*model.CountryDBRow, error) {
+	country, err := r.getCountry(ctx, "name", name)
+	if errors.Is(err, errs.ErrNotFound) {
+		return r.GetCountryByAlias(ctx, name)
+	}
+	return country, err
+}
+
+// GetCountryByCode looks a country up by its ISO 3166-1 alpha-2, alpha-3 or
+// numeric code. Anything not shaped like one of those is not found.
+func (r *ForexRepository) GetCountryByCode(ctx context.Context, code string) (*model.CountryDBRow, error) {
+	column, ok := countryCodeColumns[model.CountryCodeKind(code)]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+	return r.getCountry(ctx, column, strings.ToUpper(code))
+}
+
+// getCountry returns the country whose column equals value, with its
+// currencies, lists and overrides.
+func (r *ForexRepository) getCountry(ctx context.Context, column string, value string) (*model.CountryDBRow, error) {
+	country, err := r.getStoredCountry(ctx, column, value)
+	if err != nil {
+		return nil, err
+	}
+
+	countries := []model.CountryDBRow{*country}
+	if err := r.loadOverrides(ctx, countries); err != nil {
+		return nil, err
+	}
+	return &countries[0], nil
+}
+
+// getStoredCountry is getCountry without overrides: the row as stored.
+func (r *ForexRepository) getStoredCountry(ctx context.Context, column string, value string) (*model.CountryDBRow, error) {
 	stmt := fmt.Sprintf(`
-        SELECT
-            id, name, capital, region, population,
-            currency_code, exchange_rate, estimated_gdp,
-            flag_url, last_refreshed_at
+        SELECT %s
         FROM %s
-        WHERE name = ?
-    `, countriesTable)
+        WHERE %s = ?
+    `, countrySelectColumns, countriesTable, column)
 
-	row := r.db.Pool.QueryRowContext(ctx, stmt, name)
+	row := r.db.Pool.QueryRowContext(ctx, stmt, value)
 
-	var c model.CountryDBRow
-	err := row.Scan(
-		&c.ID, &c.Name, &c.Capital, &c.Region, &c.Population,
-		&c.CurrencyCode, &c.ExchangeRate, &c.EstimatedGDP,
-		&c.FlagURL, &c.LastRefreshedAt,
-	)
+	c, err := scanCountry(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			r.logger.Info().Err(err).Msg("No country found")
@@ -223,48 +428,72 @@ func (r *ForexRepository) GetCountryByName(ctx context.Context, name string) (*m
 		r.logger.Error().Err(err).Msg("Failed to scan row")
 		return nil, err
 	}
-	return &c, nil
+
+	countries := []model.CountryDBRow{*c}
+	if err := r.loadCurrencies(ctx, countries); err != nil {
+		return nil, err
+	}
+	if err := r.loadCountryLists(ctx, countries); err != nil {
+		return nil, err
+	}
+	return &countries[0], nil
 }
 
 func (r *ForexRepository) GetTotalCountries(ctx context.Context) (int, error) {
 	var total int
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s;", countriesTable)
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE status = ?;", countriesTable)
 
-	if err := r.db.Pool.QueryRowContext(ctx, query).Scan(&total); err != nil {
+	if err := r.db.Pool.QueryRowContext(ctx, query, model.CountryStatusActive).Scan(&total); err != nil {
 		r.logger.Error().Err(err).Msg("Failed to get total countries count")
 		return 0, err
 	}
 	return total, nil
 }
 
+// GetTop5ByGDP returns the five active countries with the highest estimated
+// GDP once overrides are applied.
 func (r *ForexRepository) GetTop5ByGDP(ctx context.Context) ([]model.CountryDBRow, error) {
-	// --- BUG FIX: Use MySQL syntax for ORDER BY ... NULLS LAST ---
-	// `estimated_gdp IS NULL ASC` puts NULLs (1) after NOT NULLs (0).
-	query := fmt.Sprintf(`
-        SELECT
-            id, name, capital, region, population,
-            currency_code, exchange_rate, estimated_gdp,
-            flag_url, last_refreshed_at
-        FROM %s
-        ORDER BY estimated_gdp IS NULL ASC, estimated_gdp DESC
-        LIMIT 5;
-    `, countriesTable)
-
-	rows, err := r.db.Pool.QueryContext(ctx, query)
+	countries, err := r.GetCountries(ctx, model.CountryFilters{SortKey: "gdp_desc"})
 	if err != nil {
-		r.logger.Error().Err(err).Msg("Failed to query top 5 countries")
 		return nil, err
 	}
-	defer rows.Close()
-
-	// Use the helper to scan rows
-	return r.scanCountries(rows)
+	return countries[:min(len(countries), 5)], nil
 }
 
+// DeleteByName removes a country and records a tombstone for it so later
+// refreshes do not bring it back.
 func (r *ForexRepository) DeleteByName(ctx context.Context, name string) error {
+	country, err := r.getStoredCountry(ctx, "name", name)
+	if err != nil {
+		return err
+	}
+	record, err := json.Marshal(country)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to encode deleted country")
+		return fmt.Errorf("failed to encode deleted country: %w", err)
+	}
+
+	tx, err := r.db.Pool.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	// The tombstone keeps the row as stored, so a restore can bring it back.
+	tombstoneSQL := fmt.Sprintf(`
+        INSERT INTO %s (name, deleted_at, record)
+        VALUES (?, ?, ?)
+        ON DUPLICATE KEY UPDATE deleted_at = VALUES(deleted_at), record = VALUES(record)
+    `, deletedCountriesTable)
+	if _, err := tx.ExecContext(ctx, tombstoneSQL, country.Name, time.Now(), string(record)); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to record country tombstone")
+		return fmt.Errorf("failed to record country tombstone: %w", err)
+	}
+
 	stmt := fmt.Sprintf("DELETE FROM %s WHERE name = ?", countriesTable)
 
-	result, err := r.db.Pool.ExecContext(ctx, stmt, name)
+	result, err := tx.ExecContext(ctx, stmt, name)
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Delete query failed!")
 		return fmt.Errorf("failed to execute delete string query: %w", err)
@@ -281,20 +510,27 @@ func (r *ForexRepository) DeleteByName(ctx context.Context, name string) error {
 		return errs.ErrNotFound
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 func (r *ForexRepository) GetStats(ctx context.Context) (*model.Stats, error) {
 	stmt := fmt.Sprintf(`
         SELECT
-            (SELECT COUNT(*) FROM %s) AS total_countries,
-            (SELECT last_refreshed_at FROM %s WHERE id = 1) AS last_refreshed_at;
+            (SELECT COUNT(*) FROM %[1]s WHERE status = 'active') AS total_countries,
+            s.last_refreshed_at, s.countries_refreshed_at,
+            s.rates_refreshed_at, s.rates_stale, s.base_currency
+        FROM %[2]s s
+        WHERE s.id = 1;
     `, countriesTable, appStatusTable)
 
 	row := r.db.Pool.QueryRowContext(ctx, stmt)
 
 	var stats model.Stats
-	if err := row.Scan(&stats.TotalCountries, &stats.LastRefreshedAt); err != nil {
+	err := row.Scan(
+		&stats.TotalCountries, &stats.LastRefreshedAt, &stats.CountriesRefreshedAt,
+		&stats.RatesRefreshedAt, &stats.RatesStale, &stats.BaseCurrency,
+	)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			r.logger.Warn().Msg("No stats row found (app_status table might be empty)")
 			// Return an empty/zero struct is fine
@@ -304,31 +540,228 @@ func (r *ForexRepository) GetStats(ctx context.Context) (*model.Stats, error) {
 		return nil, fmt.Errorf("failed to scan stats: %w", err)
 	}
 
+	stats.Sources, err = r.GetUpstreamSources(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upstream sources: %w", err)
+	}
+
 	return &stats, nil
 }
 
+// reconcileMissingCountries applies the reconcile mode to stored countries
+// that are not in temp_countries. Countries that come back are reactivated
+// by the merge itself.
+func (r *ForexRepository) reconcileMissingCountries(ctx context.Context, tx *sql.Tx, reconcile string, refreshTime time.Time) error {
+	var (
+		stmt string
+		args []any
+	)
+	switch reconcile {
+	case model.ReconcileDelete:
+		stmt = fmt.Sprintf(`
+            DELETE c FROM %s c
+            LEFT JOIN temp_countries t ON t.name = c.name
+            WHERE t.name IS NULL
+        `, countriesTable)
+	case model.ReconcileInactive:
+		stmt = fmt.Sprintf(`
+            UPDATE %s c
+            LEFT JOIN temp_countries t ON t.name = c.name
+            SET c.status = ?, c.inactive_since = ?
+            WHERE t.name IS NULL AND c.status = ?
+        `, countriesTable)
+		args = []any{model.CountryStatusInactive, refreshTime, model.CountryStatusActive}
+	default:
+		return nil
+	}
+
+	result, err := tx.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		r.logger.Error().Err(err).Str("mode", reconcile).Msg("Failed to reconcile missing countries")
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		r.logger.Info().Int64("countries", affected).Str("mode", reconcile).Msg("Reconciled countries missing upstream")
+	}
+	return nil
+}
+
+// replaceCountryCurrencies rewrites the currency list of every country in
+// temp_countries. Must run after the merge so new countries have an id.
+func (r *ForexRepository) replaceCountryCurrencies(ctx context.Context, tx *sql.Tx, rows []model.CountryDBRow) error {
+	dropTempTableSQL := `DROP TEMPORARY TABLE IF EXISTS temp_country_currencies;`
+	if _, err := tx.ExecContext(ctx, dropTempTableSQL); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to drop old currencies temporary table")
+		return err
+	}
+
+	createTempTableSQL := `
+        CREATE TEMPORARY TABLE temp_country_currencies (
+            country_name VARCHAR(256) NOT NULL,
+            currency_code VARCHAR(20) NOT NULL,
+            currency_name VARCHAR(256),
+            currency_symbol VARCHAR(32),
+            is_primary BOOLEAN NOT NULL,
+            position INT NOT NULL,
+            exchange_rate DECIMAL(15, 6),
+            rate_source VARCHAR(64),
+            PRIMARY KEY (country_name, currency_code)
+        ) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+    `
+	if _, err := tx.ExecContext(ctx, createTempTableSQL); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create currencies temporary table")
+		return err
+	}
+
+	type currencyRow struct {
+		countryName string
+		position    int
+		currency    model.CountryCurrencyDBRow
+	}
+	var currencyRows []currencyRow
+	for _, row := range rows {
+		for i, currency := range row.Currencies {
+			currencyRows = append(currencyRows, currencyRow{row.Name, i, currency})
+		}
+	}
+
+	columns := []string{
+		"country_name", "currency_code", "currency_name", "currency_symbol",
+		"is_primary", "position", "exchange_rate", "rate_source",
+	}
+	err := r.insertBatches(ctx, tx, "INSERT", "temp_country_currencies", columns, len(currencyRows), func(i int) []any {
+		c := currencyRows[i]
+		return []any{
+			c.countryName, c.currency.Code, c.currency.Name, c.currency.Symbol,
+			c.currency.IsPrimary, c.position, c.currency.ExchangeRate, c.currency.RateSource,
+		}
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to bulk insert currencies to temp table")
+		return err
+	}
+
+	deleteSQL := fmt.Sprintf(`
+        DELETE cc FROM %s cc
+        JOIN %s c ON c.id = cc.country_id
+        JOIN temp_countries t ON t.name = c.name
+    `, countryCurrenciesTable, countriesTable)
+	if _, err = tx.ExecContext(ctx, deleteSQL); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to clear refreshed country currencies")
+		return err
+	}
+
+	insertSQL := fmt.Sprintf(`
+        INSERT INTO %s (
+            country_id, currency_code, currency_name, currency_symbol,
+            is_primary, position, exchange_rate, rate_source
+        )
+        SELECT
+            c.id, t.currency_code, t.currency_name, t.currency_symbol,
+            t.is_primary, t.position, t.exchange_rate, t.rate_source
+        FROM temp_country_currencies t
+        JOIN %s c ON c.name = t.country_name
+    `, countryCurrenciesTable, countriesTable)
+	if _, err = tx.ExecContext(ctx, insertSQL); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to insert country currencies")
+		return err
+	}
+
+	return nil
+}
+
+// loadCurrencies attaches the currency list to each country, in position
+// order, with a single query.
+func (r *ForexRepository) loadCurrencies(ctx context.Context, countries []model.CountryDBRow) error {
+	if len(countries) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*model.CountryDBRow, len(countries))
+	placeholders := make([]string, 0, len(countries))
+	args := make([]any, 0, len(countries))
+	for i := range countries {
+		byID[countries[i].ID] = &countries[i]
+		placeholders = append(placeholders, "?")
+		args = append(args, countries[i].ID)
+	}
+
+	query := fmt.Sprintf(`
+        SELECT country_id, currency_code, currency_name, currency_symbol, is_primary, exchange_rate, rate_source
+        FROM %s
+        WHERE country_id IN (%s)
+        ORDER BY country_id, position
+    `, countryCurrenciesTable, strings.Join(placeholders, ","))
+
+	rows, err := r.db.Pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query country currencies")
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var countryID int64
+		var c model.CountryCurrencyDBRow
+		if err := rows.Scan(&countryID, &c.Code, &c.Name, &c.Symbol, &c.IsPrimary, &c.ExchangeRate, &c.RateSource); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan country currency row")
+			return err
+		}
+		if country, ok := byID[countryID]; ok {
+			country.Currencies = append(country.Currencies, c)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return err
+	}
+
+	return nil
+}
+
+// insertBatches bulk inserts n rows into table in chunks of batchSize. verb
+// is the statement keyword, e.g. "INSERT" or "INSERT IGNORE". rowArgs returns
+// the values of row i in column order.
+func (r *ForexRepository) insertBatches(ctx context.Context, tx *sql.Tx, verb, table string, columns []string, n int, rowArgs func(i int) []any) error {
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	stmtSQL := fmt.Sprintf("%s INTO %s (%s) VALUES %%s", verb, table, strings.Join(columns, ", "))
+
+	for i := 0; i < n; i += batchSize {
+		end := i + batchSize
+		if end > n {
+			end = n
+		}
+
+		valueStrings := make([]string, 0, end-i)
+		valueArgs := make([]any, 0, (end-i)*len(columns))
+
+		for j := i; j < end; j++ {
+			valueStrings = append(valueStrings, placeholder)
+			valueArgs = append(valueArgs, rowArgs(j)...)
+		}
+
+		batchStmt := fmt.Sprintf(stmtSQL, strings.Join(valueStrings, ","))
+
+		if _, err := tx.ExecContext(ctx, batchStmt, valueArgs...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // --- REFACTOR: Private helper to reduce code duplication ---
 // scanCountries iterates over sql.Rows and scans them into a slice.
 func (r *ForexRepository) scanCountries(rows *sql.Rows) ([]model.CountryDBRow, error) {
 	countries := []model.CountryDBRow{}
 	for rows.Next() {
-		var c model.CountryDBRow
-		if err := rows.Scan(
-			&c.ID,
-			&c.Name,
-			&c.Capital,
-			&c.Region,
-			&c.Population,
-			&c.CurrencyCode,
-			&c.ExchangeRate,
-			&c.EstimatedGDP,
-			&c.FlagURL,
-			&c.LastRefreshedAt,
-		); err != nil {
+		c, err := scanCountry(rows)
+		if err != nil {
 			r.logger.Error().Err(err).Msg("Failed to scan country row")
 			return nil, err
 		}
-		countries = append(countries, c)
+		countries = append(countries, *c)
 	}
 
 	// Always check for an error from the rows.Next() loop
@@ -339,3 +772,155 @@ func (r *ForexRepository) scanCountries(rows *sql.Rows) ([]model.CountryDBRow, e
 
 	return countries, nil
 }
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanCountry scans one row selected with countrySelectColumns.
+func scanCountry(row rowScanner) (*model.CountryDBRow, error) {
+	var c model.CountryDBRow
+	if err := row.Scan(
+		&c.ID,
+		&c.Name,
+		&c.Capital,
+		&c.Region,
+		&c.Population,
+		&c.CurrencyCode,
+		&c.ExchangeRate,
+		&c.EstimatedGDP,
+		&c.FlagURL,
+		&c.LastRefreshedAt,
+		&c.RateSource,
+		&c.RatesStale,
+		&c.GDPMethod,
+		&c.Status,
+		&c.InactiveSince,
+		&c.CountrySource,
+		&c.CountrySourceURL,
+		&c.CountriesFetchedAt,
+		&c.RateSourceURL,
+		&c.RatesUpdatedAt,
+		&c.RatesFetchedAt,
+		&c.Alpha2Code,
+		&c.Alpha3Code,
+		&c.Subregion,
+		&c.Area,
+		&c.NumericCode,
+	); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetStoredRates returns the exchange rate currently stored for each
+// currency, with the provider that supplied it. A refresh that cannot reach
+// its rate provider reuses these.
+func (r *ForexRepository) GetStoredRates(ctx context.Context) (*model.ExchangeRates, error) {
+	baseCurrency, err := r.GetBaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+        SELECT currency_code, MAX(exchange_rate), MAX(rate_source)
+        FROM %s
+        WHERE exchange_rate IS NOT NULL
+        GROUP BY currency_code
+    `, countryCurrenciesTable)
+
+	rows, err := r.db.Pool.QueryContext(ctx, query)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query stored exchange rates")
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := &model.ExchangeRates{
+		BaseCode: baseCurrency,
+		Rates:    map[string]float64{},
+		Sources:  map[string]string{},
+	}
+	for rows.Next() {
+		var (
+			code   string
+			rate   float64
+			source sql.NullString
+		)
+		if err := rows.Scan(&code, &rate, &source); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan stored exchange rate row")
+			return nil, err
+		}
+		stored.Rates[code] = rate
+		if source.Valid {
+			stored.Sources[code] = source.String
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// GetBaseCurrency returns the currency stored exchange rates are quoted
+// against.
+func (r *ForexRepository) GetBaseCurrency(ctx context.Context) (string, error) {
+	stmt := fmt.Sprintf("SELECT base_currency FROM %s WHERE id = 1", appStatusTable)
+
+	var baseCurrency string
+	if err := r.db.Pool.QueryRowContext(ctx, stmt).Scan(&baseCurrency); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query base currency")
+		return "", err
+	}
+	return baseCurrency, nil
+}
+
+func (r *ForexRepository) GetCountryFetchState(ctx context.Context) (*model.CountryFetchState, error) {
+	stmt := fmt.Sprintf(`
+        SELECT countries_fetched_at, validation_rules, countries_restored_at
+        FROM %s
+        WHERE id = 1
+    `, appStatusTable)
+
+	var state model.CountryFetchState
+	if err := r.db.Pool.QueryRowContext(ctx, stmt).Scan(&state.FetchedAt, &state.ValidationRules, &state.RestoredAt); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query country fetch state")
+		return nil, err
+	}
+	return &state, nil
+}
+
+// RecordCountriesFetch records that the stored countries were rebuilt from
+// a full download started at fetchedAt and validated with rules.
+func (r *ForexRepository) RecordCountriesFetch(ctx context.Context, fetchedAt time.Time, rules string) error {
+	stmt := fmt.Sprintf("UPDATE %s SET countries_fetched_at = ?, validation_rules = ? WHERE id = 1", appStatusTable)
+
+	if _, err := r.db.Pool.ExecContext(ctx, stmt, fetchedAt, rules); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to record countries fetch")
+		return err
+	}
+	return nil
+}
+
+// GetStoredRate returns the stored exchange rate of a currency against the
+// base currency, or errs.ErrNotFound if no country has a rate for it.
+func (r *ForexRepository) GetStoredRate(ctx context.Context, code string) (float64, error) {
+	stmt := fmt.Sprintf(`
+        SELECT MAX(exchange_rate)
+        FROM %s
+        WHERE currency_code = ? AND exchange_rate > 0
+    `, countryCurrenciesTable)
+
+	var rate sql.NullFloat64
+	if err := r.db.Pool.QueryRowContext(ctx, stmt, code).Scan(&rate); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to query stored exchange rate")
+		return 0, err
+	}
+	if !rate.Valid {
+		return 0, errs.ErrNotFound
+	}
+	return rate.Float64, nil
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/justinndidit/forex/internal/errs"
+)
+
+// snapshotTables are exported and restored in this order, parents before
+// the tables whose foreign keys point at them. refresh_jobs is left out:
+// queued or running jobs only mean something to the process that owns them.
+var snapshotTables = []string{
+	appStatusTable,
+	countriesTable,
+	countryCurrenciesTable,
+	countryLanguagesTable,
+	countryTimezonesTable,
+	countryCallingCodesTable,
+	countryBordersTable,
+	rateHistoryTable,
+	refreshRunsTable,
+	runChangesTable,
+	quarantinedCountriesTable,
+	deletedCountriesTable,
+	countryOverridesTable,
+	upstreamSourcesTable,
+	countryAliasesTable,
+}
+
+// snapshotTimeLayout is how timestamps are written to snapshots: the format
+// the MySQL driver itself sends, so a restore stores the same instant.
+const snapshotTimeLayout = "2006-01-02 15:04:05.999999"
+
+// SchemaVersion returns the migration level recorded by golang-migrate.
+func (r *ForexRepository) SchemaVersion(ctx context.Context) (uint, error) {
+	var (
+		version uint
+		dirty   bool
+	)
+	err := r.db.Pool.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to read schema version")
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("database schema version %d is dirty", version)
+	}
+	return version, nil
+}
+
+// ExportSnapshot calls fn with every row of the snapshot tables, read in a
+// single transaction so the tables are consistent with each other. Column
+// values are JSON-friendly: numbers, strings or nil.
+func (r *ForexRepository) ExportSnapshot(ctx context.Context, fn func(table string, row map[string]any) error) error {
+	tx, err := r.db.Pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range snapshotTables {
+		if err := r.exportTable(ctx, tx, table, fn); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *ForexRepository) exportTable(ctx context.Context, tx *sql.Tx, table string, fn func(table string, row map[string]any) error) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s ORDER BY 1", table))
+	if err != nil {
+		r.logger.Error().Err(err).Str("table", table).Msg("Failed to query snapshot table")
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		r.logger.Error().Err(err).Str("table", table).Msg("Failed to read snapshot columns")
+		return err
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			r.logger.Error().Err(err).Str("table", table).Msg("Failed to scan snapshot row")
+			return err
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			switch v := values[i].(type) {
+			case []byte:
+				row[column] = string(v)
+			case time.Time:
+				row[column] = v.UTC().Format(snapshotTimeLayout)
+			default:
+				row[column] = v
+			}
+		}
+		if err := fn(table, row); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Error during row iteration")
+		return err
+	}
+	return nil
+}
+
+// RestoreSnapshot replaces the contents of the snapshot tables with the rows
+// returned by next, which reports the end of the snapshot with io.EOF. Rows
+// must arrive in snapshotTables order. Nothing is changed unless every row
+// is stored; the returned map counts rows per table. The restore is recorded
+// in app_status so the next refresh is a full one.
+func (r *ForexRepository) RestoreSnapshot(ctx context.Context, next func() (string, map[string]any, error)) (map[string]int64, error) {
+	tx, err := r.db.Pool.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	known := make(map[string]map[string]bool, len(snapshotTables))
+	order := make(map[string]int, len(snapshotTables))
+	for i, table := range snapshotTables {
+		columns, err := tableColumns(ctx, tx, table)
+		if err != nil {
+			r.logger.Error().Err(err).Str("table", table).Msg("Failed to read table columns")
+			return nil, err
+		}
+		known[table] = columns
+		order[table] = i
+	}
+
+	// Children first, so foreign keys never point at a deleted parent.
+	for i := len(snapshotTables) - 1; i >= 0; i-- {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+snapshotTables[i]); err != nil {
+			r.logger.Error().Err(err).Str("table", snapshotTables[i]).Msg("Failed to clear table for restore")
+			return nil, err
+		}
+	}
+
+	counts := make(map[string]int64, len(snapshotTables))
+	var (
+		table   string
+		columns []string
+		pending [][]any
+	)
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		err := r.insertBatches(ctx, tx, "INSERT", table, columns, len(pending), func(i int) []any {
+			return pending[i]
+		})
+		if err != nil {
+			r.logger.Error().Err(err).Str("table", table).Msg("Failed to insert snapshot rows")
+			return err
+		}
+		counts[table] += int64(len(pending))
+		pending = pending[:0]
+		return nil
+	}
+
+	for {
+		rowTable, row, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rowOrder, ok := order[rowTable]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown table %q", errs.ErrSnapshotInvalid, rowTable)
+		}
+		if rowTable != table && table != "" && rowOrder < order[table] {
+			return nil, fmt.Errorf("%w: table %q appears after %q", errs.ErrSnapshotInvalid, rowTable, table)
+		}
+
+		rowColumns := make([]string, 0, len(row))
+		for column := range row {
+			if !known[rowTable][column] {
+				return nil, fmt.Errorf("%w: unknown column %q in table %q", errs.ErrSnapshotInvalid, column, rowTable)
+			}
+			rowColumns = append(rowColumns, column)
+		}
+		sort.Strings(rowColumns)
+
+		if rowTable != table || strings.Join(rowColumns, ",") != strings.Join(columns, ",") || len(pending) == batchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			table, columns = rowTable, rowColumns
+		}
+
+		values := make([]any, len(columns))
+		for i, column := range columns {
+			values[i] = row[column]
+		}
+		pending = append(pending, values)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	// The upstream ETag cache still describes what was fetched before the
+	// restore, so the next refresh must download countries in full.
+	statusSQL := fmt.Sprintf("UPDATE %s SET countries_restored_at = ? WHERE id = 1", appStatusTable)
+	if _, err := tx.ExecContext(ctx, statusSQL, time.Now()); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update app_status")
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit snapshot restore")
+		return nil, err
+	}
+	return counts, nil
+}
+
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT 0", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]bool, len(names))
+	for _, name := range names {
+		columns[name] = true
+	}
+	return columns, nil
+}
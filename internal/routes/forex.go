@@ -13,9 +13,24 @@ func SetupAuthRoutes(app *app.Application) *chi.Mux {
 	r.Post("/countries/refresh", app.Handler.HandleRefresh)
 	r.Get("/countries", app.Handler.HandleGetCountry)
 	r.Get("/countries/{name}", app.Handler.HandleGetCountryByName)
+	r.Get("/countries/code/{code}", app.Handler.HandleGetCountryByCode)
 	r.Get("/status", app.Handler.HandleStatus)
 	r.Get("/countries/image", app.Handler.HandleGetImage)
 	r.Delete("/countries/{name}", app.Handler.HandleDeleteCountryByName)
+	r.Get("/countries/deleted", app.Handler.HandleGetDeletedCountries)
+	r.Post("/countries/{name}/restore", app.Handler.HandleRestoreCountry)
+	r.Patch("/countries/{name}", app.Handler.HandlePatchCountry)
+	r.Get("/countries/{name}/overrides", app.Handler.HandleGetCountryOverrides)
+	r.Get("/refresh/jobs/{id}", app.Handler.HandleGetRefreshJob)
+	r.Get("/refresh/runs", app.Handler.HandleListRefreshRuns)
+	r.Get("/refresh/runs/{id}", app.Handler.HandleGetRefreshRun)
+	r.Get("/refresh/runs/{id}/diff", app.Handler.HandleGetRefreshRunDiff)
+	r.Get("/refresh/runs/{id}/quarantine", app.Handler.HandleGetRefreshRunQuarantine)
+	r.Get("/rates/{code}/history", app.Handler.HandleGetRateHistory)
+	r.Get("/admin/export", app.Handler.HandleExport)
+	r.Get("/admin/aliases", app.Handler.HandleListCountryAliases)
+	r.Put("/admin/aliases/{alias}", app.Handler.HandlePutCountryAlias)
+	r.Delete("/admin/aliases/{alias}", app.Handler.HandleDeleteCountryAlias)
 
 	r.Get("/kaithheathcheck", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
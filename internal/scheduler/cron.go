@@ -0,0 +1,180 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule yields the next activation time strictly after the given time.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+type intervalSchedule struct {
+	every time.Duration
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.every)
+}
+
+// cronSchedule is a standard five-field cron expression
+// (minute hour day-of-month month day-of-week) evaluated in local time.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+type cronField struct {
+	min, max int
+}
+
+var (
+	minuteField = cronField{0, 59}
+	hourField   = cronField{0, 23}
+	domField    = cronField{1, 31}
+	monthField  = cronField{1, 12}
+	dowField    = cronField{0, 7}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a five-field cron expression or one of the @-descriptors.
+func ParseCron(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if descriptor, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	var (
+		s   cronSchedule
+		err error
+	)
+	if s.minute, err = parseCronField(fields[0], minuteField); err != nil {
+		return nil, fmt.Errorf("cron minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], hourField); err != nil {
+		return nil, fmt.Errorf("cron hour: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], domField); err != nil {
+		return nil, fmt.Errorf("cron day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], monthField); err != nil {
+		return nil, fmt.Errorf("cron month: %w", err)
+	}
+	if s.dow, err = parseCronField(fields[4], dowField); err != nil {
+		return nil, fmt.Errorf("cron day of week: %w", err)
+	}
+	// Accept 7 as an alias for Sunday.
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+
+	return s, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		lo, hi := bounds.min, bounds.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			ends := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(ends[0]); err != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+			if hi, err = strconv.Atoi(ends[1]); err != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				// "5/15" means every 15 starting at 5.
+				hi = bounds.max
+			}
+		}
+
+		if lo < bounds.min || hi > bounds.max || lo > hi {
+			return 0, fmt.Errorf("value %q out of range %d-%d", part, bounds.min, bounds.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func (s cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Give up after five years; a valid expression always matches well
+	// before that (e.g. "0 0 29 2 *" needs at most four).
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows the classic cron rule: when both day-of-month and
+// day-of-week are restricted, a day matching either one is accepted.
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
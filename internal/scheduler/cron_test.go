@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronRejectsInvalidExpressions(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-b * * * *",
+		"@fortnightly",
+	}
+
+	for _, expr := range tests {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2025-01-01 is a Wednesday.
+	from := time.Date(2025, time.January, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", from, time.Date(2025, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"* * * * *", from.Add(20 * time.Second), time.Date(2025, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", from, time.Date(2025, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"5/15 * * * *", from, time.Date(2025, 1, 1, 10, 35, 0, 0, time.UTC)},
+		{"0 * * * *", from, time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", from, time.Date(2025, 1, 2, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", from, time.Date(2025, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{"0 0,12 * * *", from, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", from, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", from, time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", from, time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * *", from, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Day of month and day of week both restricted: either may match.
+		{"0 0 10 * 5", from, time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"@daily", from, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", from, time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", from, time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", from, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", from, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q Next(%s) = %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestIntervalNext(t *testing.T) {
+	from := time.Date(2025, time.January, 1, 10, 30, 15, 0, time.UTC)
+	s := intervalSchedule{every: 90 * time.Second}
+
+	if got, want := s.Next(from), from.Add(90*time.Second); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", from, got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/service"
+	"github.com/rs/zerolog"
+)
+
+// Scheduler periodically enqueues refresh jobs in the background.
+type Scheduler struct {
+	logger   *zerolog.Logger
+	jobs     *service.JobRunner
+	cfg      config.RefreshConfig
+	schedule Schedule
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(cfg config.RefreshConfig, logger *zerolog.Logger, jobs *service.JobRunner) (*Scheduler, error) {
+	var schedule Schedule
+	switch {
+	case cfg.Cron != "":
+		s, err := ParseCron(cfg.Cron)
+		if err != nil {
+			return nil, err
+		}
+		schedule = s
+	case cfg.Interval > 0:
+		schedule = intervalSchedule{every: time.Duration(cfg.Interval) * time.Second}
+	default:
+		return nil, errors.New("refresh schedule requires either an interval or a cron expression")
+	}
+
+	return &Scheduler{
+		logger:   logger,
+		jobs:     jobs,
+		cfg:      cfg,
+		schedule: schedule,
+	}, nil
+}
+
+// Start launches the scheduling loop. It returns immediately; call Stop to
+// end the loop.
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+
+	go s.loop(ctx)
+}
+
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	select {
+	case <-s.done:
+		s.logger.Info().Msg("refresh scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	defer close(s.done)
+
+	if s.cfg.RunOnStartup {
+		s.runOnce(ctx)
+	}
+
+	for {
+		next := s.schedule.Next(time.Now())
+		if next.IsZero() {
+			s.logger.Error().Str("cron", s.cfg.Cron).Msg("refresh schedule has no upcoming activation, scheduler exiting")
+			return
+		}
+		next = next.Add(s.jitter())
+
+		s.logger.Info().Time("next_run", next).Msg("next scheduled refresh")
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.runOnce(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context) {
+	job, err := s.jobs.Enqueue(ctx, model.RefreshTriggerScheduled)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to enqueue scheduled refresh")
+		return
+	}
+
+	s.logger.Info().Int64("job_id", job.ID).Msg("scheduled refresh enqueued")
+}
+
+func (s *Scheduler) jitter() time.Duration {
+	if s.cfg.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.cfg.Jitter) * int64(time.Second)))
+}
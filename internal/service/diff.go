@@ -0,0 +1,201 @@
+package service
+
+import (
+	"database/sql"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// diffCountries compares refreshed rows with what was stored before the
+// refresh. Stored countries missing from rows are reported as removed only
+// when reportRemovals is set, and only if they were still active. Callers
+// clear it when the country list was rebuilt from storage (degraded mode)
+// or when missing countries are kept, since nothing is removed then.
+func diffCountries(existing, rows []model.CountryDBRow, reportRemovals bool) []model.CountryChange {
+	byName := make(map[string]*model.CountryDBRow, len(existing))
+	for i := range existing {
+		byName[existing[i].Name] = &existing[i]
+	}
+
+	var changes []model.CountryChange
+	seen := make(map[string]bool, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		seen[row.Name] = true
+
+		old, ok := byName[row.Name]
+		if !ok {
+			changes = append(changes, model.CountryChange{CountryName: row.Name, ChangeType: model.CountryAdded})
+			continue
+		}
+		changes = append(changes, diffCountry(old, row)...)
+	}
+
+	if reportRemovals {
+		for i := range existing {
+			if !seen[existing[i].Name] && existing[i].Status == model.CountryStatusActive {
+				changes = append(changes, model.CountryChange{CountryName: existing[i].Name, ChangeType: model.CountryRemoved})
+			}
+		}
+	}
+
+	return changes
+}
+
+// countChanges returns how many distinct countries were added, changed and
+// removed.
+func countChanges(changes []model.CountryChange) (added, changed, removed int) {
+	counted := make(map[string]bool, len(changes))
+	for _, change := range changes {
+		if counted[change.CountryName] {
+			continue
+		}
+		counted[change.CountryName] = true
+
+		switch change.ChangeType {
+		case model.CountryAdded:
+			added++
+		case model.CountryChanged:
+			changed++
+		case model.CountryRemoved:
+			removed++
+		}
+	}
+	return added, changed, removed
+}
+
+// diffCountry lists the fields that differ between a stored row and its
+// refreshed version. Numbers are compared at the precision they are stored
+// with; last_refreshed_at is ignored because every refresh bumps it.
+func diffCountry(old, new *model.CountryDBRow) []model.CountryChange {
+	d := countryDiff{name: new.Name}
+
+	d.text("capital", old.Capital, new.Capital)
+	d.text("region", old.Region, new.Region)
+	d.text("subregion", old.Subregion, new.Subregion)
+	d.text("alpha2_code", old.Alpha2Code, new.Alpha2Code)
+	d.text("alpha3_code", old.Alpha3Code, new.Alpha3Code)
+	d.text("numeric_code", old.NumericCode, new.NumericCode)
+	d.number("area", old.Area, new.Area, 2)
+	d.number("population",
+		sql.NullFloat64{Float64: float64(old.Population), Valid: true},
+		sql.NullFloat64{Float64: float64(new.Population), Valid: true}, 0)
+	d.text("currency_code", old.CurrencyCode, new.CurrencyCode)
+	d.number("exchange_rate", old.ExchangeRate, new.ExchangeRate, 6)
+	d.number("estimated_gdp", old.EstimatedGDP, new.EstimatedGDP, 2)
+	d.text("gdp_method", old.GDPMethod, new.GDPMethod)
+	d.text("flag_url", old.FlagURL, new.FlagURL)
+	d.text("rate_source", old.RateSource, new.RateSource)
+	d.text("rates_stale", boolText(old.RatesStale), boolText(new.RatesStale))
+	d.text("status", sql.NullString{String: old.Status, Valid: true}, sql.NullString{String: new.Status, Valid: true})
+	d.currencies(old.Currencies, new.Currencies)
+	d.list("languages", languageCodes(old.Languages), languageCodes(new.Languages))
+	d.list("timezones", old.Timezones, new.Timezones)
+	d.list("calling_codes", old.CallingCodes, new.CallingCodes)
+	d.list("borders", old.Borders, new.Borders)
+
+	return d.changes
+}
+
+type countryDiff struct {
+	name    string
+	changes []model.CountryChange
+}
+
+func (d *countryDiff) add(field string, old, new sql.NullString, delta sql.NullFloat64) {
+	d.changes = append(d.changes, model.CountryChange{
+		CountryName: d.name,
+		ChangeType:  model.CountryChanged,
+		Field:       sql.NullString{String: field, Valid: true},
+		OldValue:    old,
+		NewValue:    new,
+		Delta:       delta,
+	})
+}
+
+func (d *countryDiff) text(field string, old, new sql.NullString) {
+	if old != new {
+		d.add(field, old, new, sql.NullFloat64{})
+	}
+}
+
+func (d *countryDiff) number(field string, old, new sql.NullFloat64, places int) {
+	old, new = roundDecimal(old, places), roundDecimal(new, places)
+	if old == new {
+		return
+	}
+
+	var delta sql.NullFloat64
+	if old.Valid && new.Valid {
+		delta = roundDecimal(sql.NullFloat64{Float64: new.Float64 - old.Float64, Valid: true}, places)
+	}
+	d.add(field, decimalText(old, places), decimalText(new, places), delta)
+}
+
+// currencies reports a change of the currency list as a whole and, for
+// currencies present on both sides, changes to their individual fields.
+func (d *countryDiff) currencies(old, new []model.CountryCurrencyDBRow) {
+	oldCodes := make([]string, len(old))
+	byCode := make(map[string]*model.CountryCurrencyDBRow, len(old))
+	for i := range old {
+		oldCodes[i] = old[i].Code
+		byCode[old[i].Code] = &old[i]
+	}
+	newCodes := make([]string, len(new))
+	for i := range new {
+		newCodes[i] = new[i].Code
+	}
+	d.text("currencies", codesText(oldCodes), codesText(newCodes))
+
+	for i := range new {
+		o, ok := byCode[new[i].Code]
+		if !ok {
+			continue
+		}
+		n := &new[i]
+		prefix := "currencies." + n.Code + "."
+		d.text(prefix+"name", o.Name, n.Name)
+		d.text(prefix+"symbol", o.Symbol, n.Symbol)
+		d.number(prefix+"exchange_rate", o.ExchangeRate, n.ExchangeRate, 6)
+		d.text(prefix+"rate_source", o.RateSource, n.RateSource)
+	}
+}
+
+// list reports a change of an ordered list of codes as a whole.
+func (d *countryDiff) list(field string, old, new []string) {
+	d.text(field, codesText(old), codesText(new))
+}
+
+func languageCodes(languages []model.CountryLanguage) []string {
+	codes := make([]string, len(languages))
+	for i, language := range languages {
+		codes[i] = language.Code
+	}
+	return codes
+}
+
+func roundDecimal(v sql.NullFloat64, places int) sql.NullFloat64 {
+	if !v.Valid {
+		return v
+	}
+	scale := math.Pow10(places)
+	return sql.NullFloat64{Float64: math.Round(v.Float64*scale) / scale, Valid: true}
+}
+
+func decimalText(v sql.NullFloat64, places int) sql.NullString {
+	if !v.Valid {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: strconv.FormatFloat(v.Float64, 'f', places, 64), Valid: true}
+}
+
+func boolText(v bool) sql.NullString {
+	return sql.NullString{String: strconv.FormatBool(v), Valid: true}
+}
+
+func codesText(codes []string) sql.NullString {
+	return sql.NullString{String: strings.Join(codes, ","), Valid: true}
+}
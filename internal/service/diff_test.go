@@ -0,0 +1,152 @@
+package service
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/justinndidit/forex/internal/model"
+)
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func nullFloat(f float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: f, Valid: true}
+}
+
+func diffRow(name string) model.CountryDBRow {
+	return model.CountryDBRow{
+		Name:         name,
+		Capital:      nullString("capital of " + name),
+		Population:   1000,
+		CurrencyCode: nullString("USD"),
+		ExchangeRate: nullFloat(1),
+		Status:       model.CountryStatusActive,
+		Currencies: []model.CountryCurrencyDBRow{
+			{Code: "USD", ExchangeRate: nullFloat(1)},
+		},
+		Timezones: []string{"UTC"},
+	}
+}
+
+// changeSummary flattens a change to "country type field old new delta".
+type changeSummary struct {
+	Country, Type, Field, Old, New string
+	Delta                          sql.NullFloat64
+}
+
+func summarize(changes []model.CountryChange) []changeSummary {
+	out := make([]changeSummary, len(changes))
+	for i, c := range changes {
+		out[i] = changeSummary{c.CountryName, c.ChangeType, c.Field.String, c.OldValue.String, c.NewValue.String, c.Delta}
+	}
+	return out
+}
+
+func TestDiffCountries(t *testing.T) {
+	tests := []struct {
+		name           string
+		existing       func() []model.CountryDBRow
+		rows           func() []model.CountryDBRow
+		reportRemovals bool
+		want           []changeSummary
+	}{
+		{
+			name:           "unchanged rows report nothing",
+			existing:       func() []model.CountryDBRow { return []model.CountryDBRow{diffRow("a")} },
+			rows:           func() []model.CountryDBRow { return []model.CountryDBRow{diffRow("a")} },
+			reportRemovals: true,
+			want:           []changeSummary{},
+		},
+		{
+			name:     "added and removed countries",
+			existing: func() []model.CountryDBRow { return []model.CountryDBRow{diffRow("a")} },
+			rows:     func() []model.CountryDBRow { return []model.CountryDBRow{diffRow("b")} },
+			want: []changeSummary{
+				{Country: "b", Type: model.CountryAdded},
+				{Country: "a", Type: model.CountryRemoved},
+			},
+			reportRemovals: true,
+		},
+		{
+			name:     "removals are not reported when not asked for",
+			existing: func() []model.CountryDBRow { return []model.CountryDBRow{diffRow("a"), diffRow("b")} },
+			rows:     func() []model.CountryDBRow { return []model.CountryDBRow{diffRow("b")} },
+			want:     []changeSummary{},
+		},
+		{
+			name: "inactive countries are not removed again",
+			existing: func() []model.CountryDBRow {
+				a := diffRow("a")
+				a.Status = model.CountryStatusInactive
+				return []model.CountryDBRow{a}
+			},
+			rows:           func() []model.CountryDBRow { return []model.CountryDBRow{} },
+			reportRemovals: true,
+			want:           []changeSummary{},
+		},
+		{
+			name:     "numbers compare at stored precision and carry a delta",
+			existing: func() []model.CountryDBRow { return []model.CountryDBRow{diffRow("a")} },
+			rows: func() []model.CountryDBRow {
+				a := diffRow("a")
+				a.Population = 1500
+				a.ExchangeRate = nullFloat(1.0000001)
+				a.EstimatedGDP = nullFloat(12.345)
+				return []model.CountryDBRow{a}
+			},
+			reportRemovals: true,
+			want: []changeSummary{
+				{Country: "a", Type: model.CountryChanged, Field: "population", Old: "1000", New: "1500", Delta: nullFloat(500)},
+				{Country: "a", Type: model.CountryChanged, Field: "estimated_gdp", Old: "", New: "12.35"},
+			},
+		},
+		{
+			name:     "text, currency and list fields",
+			existing: func() []model.CountryDBRow { return []model.CountryDBRow{diffRow("a")} },
+			rows: func() []model.CountryDBRow {
+				a := diffRow("a")
+				a.Capital = nullString("new capital")
+				a.Currencies = []model.CountryCurrencyDBRow{
+					{Code: "USD", ExchangeRate: nullFloat(1.5)},
+					{Code: "EUR", ExchangeRate: nullFloat(0.9)},
+				}
+				a.Timezones = []string{"UTC", "UTC+01:00"}
+				return []model.CountryDBRow{a}
+			},
+			reportRemovals: true,
+			want: []changeSummary{
+				{Country: "a", Type: model.CountryChanged, Field: "capital", Old: "capital of a", New: "new capital"},
+				{Country: "a", Type: model.CountryChanged, Field: "currencies", Old: "USD", New: "USD,EUR"},
+				{Country: "a", Type: model.CountryChanged, Field: "currencies.USD.exchange_rate", Old: "1.000000", New: "1.500000", Delta: nullFloat(0.5)},
+				{Country: "a", Type: model.CountryChanged, Field: "timezones", Old: "UTC", New: "UTC,UTC+01:00"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize(diffCountries(tt.existing(), tt.rows(), tt.reportRemovals))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffCountries =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountChanges(t *testing.T) {
+	changes := []model.CountryChange{
+		{CountryName: "a", ChangeType: model.CountryAdded},
+		{CountryName: "b", ChangeType: model.CountryChanged, Field: nullString("capital")},
+		{CountryName: "b", ChangeType: model.CountryChanged, Field: nullString("region")},
+		{CountryName: "c", ChangeType: model.CountryRemoved},
+		{CountryName: "d", ChangeType: model.CountryChanged, Field: nullString("population")},
+	}
+
+	added, changed, removed := countChanges(changes)
+	if added != 1 || changed != 2 || removed != 1 {
+		t.Errorf("countChanges = %d, %d, %d, want 1, 2, 1", added, changed, removed)
+	}
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultRefreshTimeout = 120
+	DefaultJobQueueSize   = 16
+
+	// jobBookkeepingTimeout bounds the status writes made after a job's own
+	// context may already have been cancelled.
+	jobBookkeepingTimeout = 5 * time.Second
+)
+
+// JobRunner executes refresh jobs one at a time on a background worker.
+// Job state lives in the refresh_jobs table so it survives restarts.
+type JobRunner struct {
+	logger    *zerolog.Logger
+	repo      *repository.ForexRepository
+	refresher *RefreshService
+	timeout   time.Duration
+
+	queue    chan int64
+	stopping chan struct{}
+	done     chan struct{}
+	cancel   context.CancelFunc
+}
+
+func NewJobRunner(logger *zerolog.Logger, repo *repository.ForexRepository, refresher *RefreshService, timeoutSeconds, queueSize int) *JobRunner {
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = DefaultRefreshTimeout
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultJobQueueSize
+	}
+
+	return &JobRunner{
+		logger:    logger,
+		repo:      repo,
+		refresher: refresher,
+		timeout:   time.Duration(timeoutSeconds) * time.Second,
+		queue:     make(chan int64, queueSize),
+		stopping:  make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start recovers jobs and runs left over from a previous process and
+// launches the worker. Jobs that were running are failed; jobs still queued
+// are re-run. Queued jobs of other instances may be picked up as well; each
+// job is claimed atomically, so only one worker runs it.
+func (j *JobRunner) Start(ctx context.Context) error {
+	if err := j.refresher.RecoverInterrupted(ctx); err != nil {
+		return err
+	}
+
+	queued, err := j.repo.GetQueuedRefreshJobIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range queued {
+		select {
+		case j.queue <- id:
+		default:
+			j.logger.Warn().Int64("job_id", id).Msg("refresh queue full, leaving recovered job queued")
+		}
+	}
+	if len(queued) > 0 {
+		j.logger.Info().Int("jobs", len(queued)).Msg("re-queued pending refresh jobs")
+	}
+
+	workerCtx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+	go j.work(workerCtx)
+
+	return nil
+}
+
+// Enqueue records a new queued job and hands it to the worker. Once Stop
+// has been called it returns errs.ErrRunnerStopped instead.
+func (j *JobRunner) Enqueue(ctx context.Context, trigger string) (*model.RefreshJob, error) {
+	if j.stopped() {
+		return nil, errs.ErrRunnerStopped
+	}
+
+	job, err := j.repo.CreateRefreshJob(ctx, trigger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop may have begun while the job was recorded; the worker would
+	// then never pick it up.
+	if j.stopped() {
+		return nil, j.reject(ctx, job, "Refresh job runner is stopping", errs.ErrRunnerStopped)
+	}
+	select {
+	case j.queue <- job.ID:
+		return job, nil
+	default:
+	}
+	return nil, j.reject(ctx, job, "Refresh queue is full", errs.ErrQueueFull)
+}
+
+// reject records a job that was never handed to the worker as failed and
+// returns reason, or the error recording it.
+func (j *JobRunner) reject(ctx context.Context, job *model.RefreshJob, message string, reason error) error {
+	job.Status = model.RefreshJobFailed
+	job.FinishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	job.ErrorMessage = sql.NullString{String: message, Valid: true}
+	if err := j.repo.FinishRefreshJob(ctx, job, model.RefreshJobQueued); err != nil {
+		return err
+	}
+	return reason
+}
+
+func (j *JobRunner) stopped() bool {
+	select {
+	case <-j.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// InProgress returns the refresh currently running on any instance, or nil
+// if there is none.
+func (j *JobRunner) InProgress(ctx context.Context) (*errs.RefreshInProgressError, error) {
+	return j.refresher.InProgress(ctx)
+}
+
+// Stop stops accepting work and waits for the in-flight job. If ctx expires
+// first the job is cancelled and recorded as failed.
+func (j *JobRunner) Stop(ctx context.Context) error {
+	if j.cancel == nil {
+		return nil
+	}
+	close(j.stopping)
+
+	select {
+	case <-j.done:
+		j.logger.Info().Msg("refresh job runner stopped")
+		return nil
+	case <-ctx.Done():
+		j.cancel()
+		<-j.done
+		return ctx.Err()
+	}
+}
+
+func (j *JobRunner) work(ctx context.Context) {
+	defer close(j.done)
+
+	for {
+		select {
+		case <-j.stopping:
+			return
+		case id := <-j.queue:
+			j.run(ctx, id)
+		}
+	}
+}
+
+func (j *JobRunner) run(ctx context.Context, id int64) {
+	job, err := j.repo.GetRefreshJob(ctx, id)
+	if err != nil {
+		j.logger.Error().Err(err).Int64("job_id", id).Msg("failed to load refresh job")
+		if !errors.Is(err, errs.ErrNotFound) {
+			j.abandon(&model.RefreshJob{ID: id}, err)
+		}
+		return
+	}
+	if job.Status != model.RefreshJobQueued {
+		// Recovered from the database and since taken by another instance.
+		return
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, j.timeout)
+	result, err := j.refresher.Refresh(runCtx, job.Trigger, job)
+	cancel()
+
+	switch {
+	case errors.Is(err, errs.ErrJobClaimed):
+		j.logger.Info().Int64("job_id", id).Msg("refresh job claimed by another worker")
+	case job.Status == model.RefreshJobQueued:
+		// The refresh never got as far as claiming the job.
+		j.logger.Error().Err(err).Int64("job_id", id).Msg("failed to start refresh job")
+		j.abandon(job, err)
+	case err != nil:
+		j.logger.Error().Err(err).Int64("job_id", id).Msg("refresh job failed")
+	default:
+		j.logger.Info().
+			Int64("job_id", id).
+			Int64("run_id", result.RunID).
+			Str("status", job.Status).
+			Int("countries", result.CountriesProcessed).
+			Int("quarantined", result.Quarantined).
+			Int64("duration_ms", job.DurationMs.Int64).
+			Msg("refresh job succeeded")
+	}
+}
+
+// abandon records a job that could not be started as failed, unless another
+// worker has claimed it meanwhile.
+func (j *JobRunner) abandon(job *model.RefreshJob, err error) {
+	job.Status = model.RefreshJobFailed
+	job.FinishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	message, details := describeRefreshError(err)
+	job.ErrorMessage = sql.NullString{String: message, Valid: true}
+	job.ErrorDetails = sql.NullString{String: details, Valid: true}
+	finishJob(j.repo, j.logger, job, model.RefreshJobQueued)
+}
+
+// recordJobOutcome fills in job from the result of the refresh it ran.
+func recordJobOutcome(job *model.RefreshJob, result *RefreshResult, err error) {
+	finishedAt := time.Now()
+	job.FinishedAt = sql.NullTime{Time: finishedAt, Valid: true}
+	job.DurationMs = sql.NullInt64{Int64: finishedAt.Sub(job.StartedAt.Time).Milliseconds(), Valid: true}
+
+	if err != nil {
+		job.Status = model.RefreshJobFailed
+		message, details := describeRefreshError(err)
+		job.ErrorMessage = sql.NullString{String: message, Valid: true}
+		job.ErrorDetails = sql.NullString{String: details, Valid: true}
+		return
+	}
+
+	job.Status = model.RefreshJobSucceeded
+	job.CountriesProcessed = sql.NullInt64{Int64: int64(result.CountriesProcessed), Valid: true}
+	job.CountriesRefreshed = sql.NullBool{Bool: result.Parts.Countries, Valid: true}
+	job.RatesRefreshed = sql.NullBool{Bool: result.Parts.Rates, Valid: true}
+	if len(result.FailedSources) > 0 {
+		job.Status = model.RefreshJobPartial
+		job.ErrorMessage = sql.NullString{String: "External data source unavailable", Valid: true}
+		job.ErrorDetails = sql.NullString{String: failedSourcesDetails(result.FailedSources), Valid: true}
+	}
+}
+
+// finishJob stores the outcome of a job that is still in status from. The
+// job's own context may have expired, so the write gets a fresh one.
+func finishJob(repo *repository.ForexRepository, logger *zerolog.Logger, job *model.RefreshJob, from string) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobBookkeepingTimeout)
+	defer cancel()
+
+	err := repo.FinishRefreshJob(ctx, job, from)
+	switch {
+	case errors.Is(err, errs.ErrNotFound):
+		logger.Warn().Int64("job_id", job.ID).Str("status", job.Status).Msg("refresh job was finished by another worker, outcome not recorded")
+	case err != nil:
+		logger.Error().Err(err).Int64("job_id", job.ID).Str("status", job.Status).Msg("failed to record refresh job outcome")
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/justinndidit/forex/internal/model"
+)
+
+func TestRebaseRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    model.ExchangeRates
+		base    string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{
+			name: "cross rates through the new base",
+			data: model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"USD": 1, "EUR": 0.8, "NGN": 1600, "GBP": 0.5}},
+			base: "EUR",
+			want: map[string]float64{"USD": 1.25, "EUR": 1, "NGN": 2000, "GBP": 0.625},
+		},
+		{
+			name: "new base added at one when the old base was missing",
+			data: model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"EUR": 0.5, "JPY": 75}},
+			base: "EUR",
+			want: map[string]float64{"EUR": 1, "JPY": 150},
+		},
+		{
+			name: "same base, any case",
+			data: model.ExchangeRates{BaseCode: "usd", Rates: map[string]float64{"EUR": 0.8}},
+			base: "USD",
+			want: map[string]float64{"USD": 1, "EUR": 0.8},
+		},
+		{
+			name: "no base code is taken as quoted against base",
+			data: model.ExchangeRates{Rates: map[string]float64{"USD": 1.1, "EUR": 1}},
+			base: "EUR",
+			want: map[string]float64{"USD": 1.1, "EUR": 1},
+		},
+		{
+			name:    "no rate for the new base",
+			data:    model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"USD": 1, "GBP": 0.5}},
+			base:    "EUR",
+			wantErr: true,
+		},
+		{
+			name:    "non-positive rate for the new base",
+			data:    model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"USD": 1, "EUR": 0}},
+			base:    "EUR",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data
+			got, err := rebaseRates(&data, tt.base)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("rebaseRates succeeded with %v, want an error", got.Rates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rebaseRates: %v", err)
+			}
+
+			if got.BaseCode != tt.base {
+				t.Errorf("BaseCode = %q, want %q", got.BaseCode, tt.base)
+			}
+			if len(got.Rates) != len(tt.want) {
+				t.Errorf("Rates = %v, want %v", got.Rates, tt.want)
+			}
+			for code, want := range tt.want {
+				if rate, ok := got.Rates[code]; !ok || math.Abs(rate-want) > 1e-9 {
+					t.Errorf("Rates[%s] = %v, want %v", code, rate, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRebaseRatesLeavesCrossRatedInputAlone(t *testing.T) {
+	data := &model.ExchangeRates{BaseCode: "USD", Rates: map[string]float64{"USD": 1, "EUR": 0.8}}
+
+	if _, err := rebaseRates(data, "EUR"); err != nil {
+		t.Fatalf("rebaseRates: %v", err)
+	}
+
+	if data.BaseCode != "USD" || data.Rates["USD"] != 1 || data.Rates["EUR"] != 0.8 {
+		t.Errorf("input changed to %s %v", data.BaseCode, data.Rates)
+	}
+}
@@ -0,0 +1,882 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justinndidit/forex/internal/config"
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/estimator"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/provider"
+	"github.com/justinndidit/forex/internal/repository"
+	"github.com/justinndidit/forex/internal/upstream"
+	"github.com/justinndidit/forex/internal/util"
+	"github.com/rs/zerolog"
+)
+
+// DefaultBaseCurrency is used when REFRESH_BASE_CURRENCY is not set.
+const DefaultBaseCurrency = "USD"
+
+// RefreshService runs the fetch-transform-persist pipeline shared by the
+// manual refresh endpoint and the background scheduler. It only orchestrates
+// the configured providers and the repository.
+type RefreshService struct {
+	logger    *zerolog.Logger
+	repo      *repository.ForexRepository
+	imgGen    *util.ImageService
+	cfg       config.RefreshConfig
+	countries provider.CountrySource
+	rates     provider.RateSource
+	gdp       estimator.GDPEstimator
+
+	// mu serialises refreshes started from this process so a scheduled run
+	// and a manual one never race on the temp table or the summary image.
+	mu sync.Mutex
+	// summaries tracks summary images still being written in the background.
+	summaries sync.WaitGroup
+}
+
+type RefreshResult struct {
+	RunID              int64
+	RefreshedAt        time.Time
+	CountriesProcessed int
+	// Quarantined counts upstream countries rejected by validation; dropped
+	// currencies of stored countries are not counted.
+	Quarantined int
+	Parts       model.RefreshedParts
+	// FailedSources lists the providers that could not be fetched when the
+	// refresh ran in degraded mode.
+	FailedSources []string
+}
+
+func NewRefreshService(logger *zerolog.Logger, repo *repository.ForexRepository, imgGen *util.ImageService, cfg config.RefreshConfig, countries provider.CountrySource, rates provider.RateSource, gdp estimator.GDPEstimator) *RefreshService {
+	return &RefreshService{
+		logger:    logger,
+		repo:      repo,
+		imgGen:    imgGen,
+		cfg:       cfg,
+		countries: countries,
+		rates:     rates,
+		gdp:       gdp,
+	}
+}
+
+// Refresh runs the pipeline once and records it in refresh_runs. job is the
+// queued refresh job that started it, or nil; it is claimed and finished
+// while the refresh lock is held, and errs.ErrJobClaimed is returned if
+// another worker claimed it first. If a refresh is already running on any
+// instance it returns a *errs.RefreshInProgressError without recording a run
+// or touching job.
+func (s *RefreshService) Refresh(ctx context.Context, trigger string, job *model.RefreshJob) (*RefreshResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, err := s.repo.TryLock(ctx, repository.RefreshLockName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take refresh lock: %w", err)
+	}
+	if lock == nil {
+		return nil, s.inProgressError(ctx)
+	}
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), jobBookkeepingTimeout)
+		defer cancel()
+		lock.Release(releaseCtx)
+	}()
+
+	// Holding the lock, any job or run still marked running was interrupted.
+	if err := s.failInterrupted(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to fail interrupted refreshes")
+	}
+
+	var jobID int64
+	if job != nil {
+		startedAt := time.Now()
+		claimed, err := s.repo.ClaimRefreshJob(ctx, job.ID, startedAt)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return nil, errs.ErrJobClaimed
+		}
+		job.Status = model.RefreshJobRunning
+		job.StartedAt = sql.NullTime{Time: startedAt, Valid: true}
+		jobID = job.ID
+	}
+
+	run := &model.RefreshRun{
+		JobID:         sql.NullInt64{Int64: jobID, Valid: jobID > 0},
+		Trigger:       trigger,
+		Status:        model.RefreshJobRunning,
+		StartedAt:     time.Now(),
+		CountrySource: sql.NullString{String: s.countries.Name(), Valid: true},
+		RateSource:    sql.NullString{String: s.rates.Name(), Valid: true},
+	}
+	result, err := s.runRecorded(ctx, run)
+	if job != nil {
+		// Finish the job before the lock is released, so no other instance
+		// takes it for interrupted.
+		recordJobOutcome(job, result, err)
+		finishJob(s.repo, s.logger, job, model.RefreshJobRunning)
+	}
+	// Hold the lock until the summary image is written, so no other
+	// instance writes it at the same time.
+	s.summaries.Wait()
+	return result, err
+}
+
+// runRecorded runs the pipeline as run, recording the run's outcome.
+func (s *RefreshService) runRecorded(ctx context.Context, run *model.RefreshRun) (*RefreshResult, error) {
+	if err := s.repo.CreateRefreshRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	result, err := s.refresh(ctx, run)
+	s.finishRun(run, result, err)
+	if err != nil {
+		return nil, err
+	}
+	result.RunID = run.ID
+	return result, nil
+}
+
+// RecoverInterrupted fails the jobs and runs left running by a process that
+// stopped mid-refresh. If another instance holds the refresh lock it is left to that
+// instance, which does the same when it takes the lock.
+func (s *RefreshService) RecoverInterrupted(ctx context.Context) error {
+	lock, err := s.repo.TryLock(ctx, repository.RefreshLockName)
+	if err != nil {
+		return fmt.Errorf("failed to take refresh lock: %w", err)
+	}
+	if lock == nil {
+		return nil
+	}
+	defer lock.Release(ctx)
+
+	return s.failInterrupted(ctx)
+}
+
+// failInterrupted must be called with the refresh lock held.
+func (s *RefreshService) failInterrupted(ctx context.Context) error {
+	jobs, err := s.repo.FailInterruptedRefreshJobs(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if jobs > 0 {
+		s.logger.Warn().Int64("jobs", jobs).Msg("Marked interrupted refresh jobs as failed")
+	}
+
+	runs, err := s.repo.FailInterruptedRefreshRuns(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if runs > 0 {
+		s.logger.Warn().Int64("runs", runs).Msg("Marked interrupted refresh runs as failed")
+	}
+	return nil
+}
+
+// InProgress returns the refresh currently holding the refresh lock on any
+// instance, or nil if there is none.
+func (s *RefreshService) InProgress(ctx context.Context) (*errs.RefreshInProgressError, error) {
+	held, err := s.repo.LockHeld(ctx, repository.RefreshLockName)
+	if err != nil {
+		return nil, err
+	}
+	if !held {
+		return nil, nil
+	}
+	return s.inProgressError(ctx), nil
+}
+
+// inProgressError describes the refresh holding the lock. The run is looked
+// up on a best-effort basis; the holder may not have recorded it yet.
+func (s *RefreshService) inProgressError(ctx context.Context) *errs.RefreshInProgressError {
+	inProgress := &errs.RefreshInProgressError{}
+	if run, err := s.repo.GetRunningRefreshRun(ctx); err == nil {
+		inProgress.RunID = run.ID
+	}
+	return inProgress
+}
+
+// refresh does the work of Refresh, filling in run's latencies and counts
+// as it goes.
+func (s *RefreshService) refresh(ctx context.Context, run *model.RefreshRun) (*RefreshResult, error) {
+	existing, err := s.repo.GetCountries(ctx, model.CountryFilters{IncludeInactive: true, Raw: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored countries: %w", err)
+	}
+
+	// Upstream responses are only cached once the data in them is stored,
+	// so a failed refresh is not followed by a 304 for data never stored.
+	ctx, pendingCache := upstream.DeferCache(ctx)
+
+	rules := enabledRules(s.cfg.ValidationRules)
+	fetchState, err := s.repo.GetCountryFetchState(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load country fetch state: %w", err)
+	}
+	// Restored countries and records the old rules quarantined are missing
+	// from storage, so an unchanged upstream cannot be answered from it.
+	countriesCtx := ctx
+	if fetchState.NeedsFullFetch(rulesText(rules)) {
+		countriesCtx = upstream.RequireBody(ctx)
+	}
+
+	var (
+		wg                 sync.WaitGroup
+		countriesList      []model.Country
+		countriesFetchedAt time.Time
+		exchangeData       *model.ExchangeRates
+		countriesErr       error
+		ratesErr           error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		countriesFetchedAt = start
+		countriesList, countriesErr = s.countries.FetchCountries(countriesCtx)
+		run.CountriesLatencyMs = sql.NullInt64{Int64: time.Since(start).Milliseconds(), Valid: true}
+	}()
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		exchangeData, ratesErr = s.rates.FetchRates(ctx)
+		run.RatesLatencyMs = sql.NullInt64{Int64: time.Since(start).Milliseconds(), Valid: true}
+	}()
+	wg.Wait()
+
+	base := s.baseCurrency()
+
+	// A source that answers 304 has nothing new, so what is stored is still
+	// current. With nothing stored to fall back on, ask for the cached body.
+	var notModified model.RefreshedParts
+	if errors.Is(countriesErr, upstream.ErrNotModified) {
+		if stored := storedCountries(existing); len(stored) > 0 {
+			countriesList, countriesErr = stored, nil
+			notModified.Countries = true
+		} else {
+			countriesList, countriesErr = s.countries.FetchCountries(upstream.RequireBody(ctx))
+		}
+	}
+	if errors.Is(ratesErr, upstream.ErrNotModified) {
+		stored, err := s.storedRates(ctx, base)
+		if err != nil {
+			return nil, err
+		}
+		if len(stored.Rates) > 0 {
+			exchangeData, ratesErr = stored, nil
+			notModified.Rates = true
+		} else {
+			exchangeData, ratesErr = s.rates.FetchRates(upstream.RequireBody(ctx))
+		}
+	}
+	if ratesErr == nil && exchangeData != nil && !notModified.Rates {
+		exchangeData, ratesErr = rebaseRates(exchangeData, base)
+	}
+	run.CountriesNotModified = sql.NullBool{Bool: notModified.Countries, Valid: true}
+	run.RatesNotModified = sql.NullBool{Bool: notModified.Rates, Valid: true}
+
+	if countriesErr == nil && len(countriesList) == 0 {
+		countriesErr = errors.New("API returned empty or invalid data")
+	}
+	if ratesErr == nil && (exchangeData == nil || len(exchangeData.Rates) == 0) {
+		ratesErr = errors.New("API returned empty or invalid data")
+	}
+
+	var failedSources []string
+	if countriesErr != nil {
+		s.logger.Error().Err(countriesErr).Msg("Failed to fetch countries from: " + s.countries.Name())
+		failedSources = append(failedSources, s.countries.Name())
+	}
+	if ratesErr != nil {
+		s.logger.Error().Err(ratesErr).Msg("Failed to fetch exchange rates from: " + s.rates.Name())
+		failedSources = append(failedSources, s.rates.Name())
+	}
+
+	if countriesErr != nil && ratesErr != nil {
+		return nil, &errs.UpstreamError{Details: failedSourcesDetails(failedSources)}
+	}
+
+	if notModified.Countries && notModified.Rates && !hasStaleRates(existing) {
+		return s.markUnchanged(ctx, run, len(countriesList))
+	}
+
+	// Degraded mode: one upstream failed, so fill its half from what is
+	// already stored and refresh only the other half.
+	parts := model.RefreshedParts{Countries: countriesErr == nil, Rates: ratesErr == nil}
+	if !parts.Countries {
+		if len(existing) == 0 {
+			return nil, &errs.UpstreamError{Details: failedSourcesDetails(failedSources)}
+		}
+		countriesList = storedCountries(existing)
+	}
+	if !parts.Rates {
+		stored, err := s.storedRates(ctx, base)
+		if err != nil {
+			return nil, err
+		}
+		exchangeData = stored
+	}
+
+	deleted, err := s.repo.GetDeletedCountries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deleted countries: %w", err)
+	}
+
+	var invalidRates map[string]float64
+	if rules[model.RuleRate] {
+		exchangeData, invalidRates = sanitizeRates(exchangeData)
+	}
+	countriesList, quarantined := validateCountries(countriesList, invalidRates, rules)
+	quarantinedCountries := countWholeCountries(quarantined)
+	if len(quarantined) > 0 {
+		s.logger.Warn().
+			Int("countries", quarantinedCountries).
+			Int("currencies", len(quarantined)-quarantinedCountries).
+			Msg("Quarantined upstream records that failed validation")
+	}
+
+	refreshTime := time.Now()
+	rowsToInsert := withoutDeleted(buildCountryRows(countriesList, exchangeData, s.gdp, refreshTime), deleted)
+	for i := range rowsToInsert {
+		rowsToInsert[i].RatesStale = !parts.Rates
+	}
+
+	// Only data downloaded by this run gets new provenance; the rest keeps
+	// what was recorded when it was downloaded.
+	var sources []model.UpstreamSource
+	var countriesProvenance *model.Provenance
+	if parts.Countries && !notModified.Countries {
+		countriesProvenance = &model.Provenance{
+			Source:    s.countries.Name(),
+			URL:       s.countries.URL(),
+			FetchedAt: countriesFetchedAt,
+		}
+		sources = append(sources, model.NewUpstreamSource(model.SourceKindCountries, *countriesProvenance))
+	}
+	freshRates := exchangeData
+	if !parts.Rates || notModified.Rates {
+		freshRates = nil
+	} else {
+		for _, p := range exchangeData.Provenance {
+			sources = append(sources, model.NewUpstreamSource(model.SourceKindRates, p))
+		}
+	}
+	setProvenance(rowsToInsert, existing, countriesProvenance, freshRates)
+	rowsToInsert = keepQuarantined(rowsToInsert, existing, quarantined)
+	if duplicates := dropDuplicateCodes(rowsToInsert); len(duplicates) > 0 {
+		s.logger.Warn().Strs("countries", duplicates).Msg("Dropped ISO codes already used by another country")
+	}
+	changes := diffCountries(existing, rowsToInsert, parts.Countries && !keepsMissing(s.cfg.ReconcileMode))
+
+	if err := s.repo.UpdateCountries(ctx, rowsToInsert, refreshTime, parts, s.cfg.ReconcileMode, base); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to update database")
+		return nil, fmt.Errorf("failed to update countries: %w", err)
+	}
+	pendingCache.Commit()
+	if parts.Countries && !notModified.Countries {
+		if err := s.repo.RecordCountriesFetch(ctx, countriesFetchedAt, rulesText(rules)); err != nil {
+			s.logger.Error().Err(err).Msg("Failed to record countries fetch")
+		}
+	}
+	added, changed, removed := countChanges(changes)
+	run.CountriesInserted = sql.NullInt64{Int64: int64(added), Valid: true}
+	run.CountriesUpdated = sql.NullInt64{Int64: int64(changed), Valid: true}
+	run.CountriesUnchanged = sql.NullInt64{Int64: int64(len(rowsToInsert) - added - changed), Valid: true}
+	run.CountriesRemoved = sql.NullInt64{Int64: int64(removed), Valid: true}
+	run.CountriesQuarantined = sql.NullInt64{Int64: int64(quarantinedCountries), Valid: true}
+	if err := s.repo.SaveRefreshRunChanges(ctx, run.ID, changes); err != nil {
+		s.logger.Error().Err(err).Int64("run_id", run.ID).Msg("Failed to record refresh diff")
+	}
+	if err := s.repo.SaveQuarantinedCountries(ctx, run.ID, quarantined); err != nil {
+		s.logger.Error().Err(err).Int64("run_id", run.ID).Msg("Failed to record quarantined countries")
+	}
+	if err := s.repo.SaveUpstreamSources(ctx, sources); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to record upstream sources")
+	}
+
+	if parts.Rates && !notModified.Rates {
+		s.recordRateHistory(ctx, exchangeData, refreshTime)
+	}
+	s.summaries.Add(1)
+	go func() {
+		defer s.summaries.Done()
+		s.generateAndLogSummary(context.Background(), refreshTime)
+	}()
+
+	if len(failedSources) > 0 {
+		s.logger.Warn().
+			Bool("countries_refreshed", parts.Countries).
+			Bool("rates_refreshed", parts.Rates).
+			Msg("Refresh completed in degraded mode")
+	}
+
+	return &RefreshResult{
+		RefreshedAt:        refreshTime,
+		CountriesProcessed: len(rowsToInsert),
+		Quarantined:        quarantinedCountries,
+		Parts:              parts,
+		FailedSources:      failedSources,
+	}, nil
+}
+
+// finishRun records the outcome of a run. The run's own context may have
+// expired, so the write gets a fresh one.
+func (s *RefreshService) finishRun(run *model.RefreshRun, result *RefreshResult, err error) {
+	finishedAt := time.Now()
+	run.FinishedAt = sql.NullTime{Time: finishedAt, Valid: true}
+	run.DurationMs = sql.NullInt64{Int64: finishedAt.Sub(run.StartedAt).Milliseconds(), Valid: true}
+
+	if err != nil {
+		run.Status = model.RefreshJobFailed
+		message, details := describeRefreshError(err)
+		run.ErrorMessage = sql.NullString{String: message, Valid: true}
+		run.ErrorDetails = sql.NullString{String: details, Valid: true}
+	} else {
+		run.Status = model.RefreshJobSucceeded
+		run.CountriesRefreshed = sql.NullBool{Bool: result.Parts.Countries, Valid: true}
+		run.RatesRefreshed = sql.NullBool{Bool: result.Parts.Rates, Valid: true}
+		if len(result.FailedSources) > 0 {
+			run.Status = model.RefreshJobPartial
+			run.ErrorMessage = sql.NullString{String: "External data source unavailable", Valid: true}
+			run.ErrorDetails = sql.NullString{String: failedSourcesDetails(result.FailedSources), Valid: true}
+		}
+	}
+
+	finishCtx, cancel := context.WithTimeout(context.Background(), jobBookkeepingTimeout)
+	defer cancel()
+	if err := s.repo.FinishRefreshRun(finishCtx, run); err != nil {
+		s.logger.Error().Err(err).Int64("run_id", run.ID).Str("status", run.Status).Msg("Failed to record refresh run outcome")
+	}
+}
+
+func failedSourcesDetails(names []string) string {
+	return fmt.Sprintf("Could not fetch data from: %s", strings.Join(names, ", "))
+}
+
+// describeRefreshError maps a refresh failure to the message and details
+// stored on its job and run.
+func describeRefreshError(err error) (string, string) {
+	var (
+		upstreamErr   *errs.UpstreamError
+		inProgressErr *errs.RefreshInProgressError
+	)
+	switch {
+	case errors.As(err, &upstreamErr):
+		return "External data source unavailable", upstreamErr.Details
+	case errors.As(err, &inProgressErr):
+		return "Refresh already in progress", inProgressErr.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Refresh timed out", err.Error()
+	case errors.Is(err, context.Canceled):
+		return "Refresh cancelled", err.Error()
+	default:
+		return "Internal server error", err.Error()
+	}
+}
+
+// keepsMissing reports whether the reconcile mode leaves countries missing
+// upstream untouched.
+func keepsMissing(reconcile string) bool {
+	return reconcile != model.ReconcileInactive && reconcile != model.ReconcileDelete
+}
+
+// withoutDeleted drops rows for countries that were deleted by hand, so
+// they are neither reinserted nor reported in the diff.
+func withoutDeleted(rows []model.CountryDBRow, deleted []model.DeletedCountry) []model.CountryDBRow {
+	if len(deleted) == 0 {
+		return rows
+	}
+
+	tombstones := make(map[string]bool, len(deleted))
+	for _, d := range deleted {
+		tombstones[strings.ToLower(d.Name)] = true
+	}
+
+	kept := rows[:0]
+	for _, row := range rows {
+		if !tombstones[row.Name] {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+// markUnchanged finishes a refresh in which neither source had anything new.
+// The stored rows are left as they are; only the refresh time moves forward.
+func (s *RefreshService) markUnchanged(ctx context.Context, run *model.RefreshRun, stored int) (*RefreshResult, error) {
+	refreshTime := time.Now()
+	parts := model.RefreshedParts{Countries: true, Rates: true}
+	if err := s.repo.MarkRefreshed(ctx, refreshTime, parts); err != nil {
+		return nil, fmt.Errorf("failed to update refresh status: %w", err)
+	}
+
+	run.CountriesInserted = sql.NullInt64{Int64: 0, Valid: true}
+	run.CountriesUpdated = sql.NullInt64{Int64: 0, Valid: true}
+	run.CountriesUnchanged = sql.NullInt64{Int64: int64(stored), Valid: true}
+	run.CountriesRemoved = sql.NullInt64{Int64: 0, Valid: true}
+	s.logger.Info().Msg("Upstream data not modified, skipping update")
+
+	return &RefreshResult{
+		RefreshedAt:        refreshTime,
+		CountriesProcessed: stored,
+		Parts:              parts,
+	}, nil
+}
+
+// hasStaleRates reports whether any active row still carries rates kept from
+// a degraded refresh; those rows need rewriting even if nothing upstream
+// changed.
+func hasStaleRates(rows []model.CountryDBRow) bool {
+	for _, row := range rows {
+		if row.Status == model.CountryStatusActive && row.RatesStale {
+			return true
+		}
+	}
+	return false
+}
+
+// storedCountries turns stored active rows back into upstream-shaped
+// countries so fresh rates can be applied to them when the country source is
+// down.
+func storedCountries(rows []model.CountryDBRow) []model.Country {
+	countries := make([]model.Country, 0, len(rows))
+	for _, row := range rows {
+		if row.Status != model.CountryStatusActive {
+			continue
+		}
+		country := model.Country{
+			Name:       row.Name,
+			Capital:    row.Capital.String,
+			Region:     row.Region.String,
+			Population: row.Population,
+			FlagURL:    row.FlagURL.String,
+
+			Alpha2Code:   row.Alpha2Code.String,
+			Alpha3Code:   row.Alpha3Code.String,
+			NumericCode:  row.NumericCode.String,
+			Subregion:    row.Subregion.String,
+			Area:         row.Area.Float64,
+			Languages:    row.Languages,
+			Timezones:    row.Timezones,
+			CallingCodes: row.CallingCodes,
+			Borders:      row.Borders,
+		}
+		for _, currency := range row.Currencies {
+			country.Currencies = append(country.Currencies, model.CountryCurrency{
+				Code:   currency.Code,
+				Name:   currency.Name.String,
+				Symbol: currency.Symbol.String,
+			})
+		}
+		countries = append(countries, country)
+	}
+	return countries
+}
+
+func buildCountryRows(countriesList []model.Country, exchangeData *model.ExchangeRates, gdp estimator.GDPEstimator, refreshTime time.Time) []model.CountryDBRow {
+	rowsToInsert := make([]model.CountryDBRow, 0, len(countriesList))
+
+	for _, country := range countriesList {
+		dbRow := model.CountryDBRow{
+			Name:       strings.ToLower(country.Name),
+			Population: country.Population,
+			Capital: sql.NullString{
+				String: strings.ToLower(country.Capital),
+				Valid:  country.Capital != "",
+			},
+			Region: sql.NullString{
+				String: strings.ToLower(country.Region),
+				Valid:  country.Region != "",
+			},
+			FlagURL: sql.NullString{
+				String: country.FlagURL,
+				Valid:  country.FlagURL != "",
+			},
+			LastRefreshedAt: sql.NullTime{
+				Time:  refreshTime,
+				Valid: true,
+			},
+			Status: model.CountryStatusActive,
+			Alpha2Code: sql.NullString{
+				String: strings.ToUpper(country.Alpha2Code),
+				Valid:  country.Alpha2Code != "",
+			},
+			Alpha3Code: sql.NullString{
+				String: strings.ToUpper(country.Alpha3Code),
+				Valid:  country.Alpha3Code != "",
+			},
+			NumericCode: sql.NullString{
+				String: country.NumericCode,
+				Valid:  country.NumericCode != "",
+			},
+			Subregion: sql.NullString{
+				String: strings.ToLower(country.Subregion),
+				Valid:  country.Subregion != "",
+			},
+			Area: sql.NullFloat64{
+				Float64: country.Area,
+				Valid:   country.Area > 0,
+			},
+			Languages:    country.Languages,
+			Timezones:    country.Timezones,
+			CallingCodes: country.CallingCodes,
+			Borders:      upperAll(country.Borders),
+			AltSpellings: country.AltSpellings,
+		}
+		dbRow.Currencies = buildCurrencyRows(country.Currencies, exchangeData)
+
+		if len(dbRow.Currencies) > 0 {
+			primary := dbRow.Currencies[0]
+			dbRow.CurrencyCode = sql.NullString{String: primary.Code, Valid: true}
+
+			if primary.ExchangeRate.Valid {
+				dbRow.ExchangeRate = primary.ExchangeRate
+				dbRow.RateSource = primary.RateSource
+			}
+		}
+
+		estimate, ok := gdp.Estimate(estimator.Input{
+			Name:         dbRow.Name,
+			Alpha3Code:   dbRow.Alpha3Code.String,
+			Population:   country.Population,
+			ExchangeRate: dbRow.ExchangeRate.Float64,
+			HasRate:      dbRow.ExchangeRate.Valid,
+			USDRate:      exchangeData.Rates["USD"],
+		})
+		switch {
+		case ok:
+			dbRow.EstimatedGDP = sql.NullFloat64{Float64: estimate.Value, Valid: true}
+			dbRow.GDPMethod = sql.NullString{String: estimate.Method, Valid: true}
+		case len(dbRow.Currencies) == 0:
+			dbRow.EstimatedGDP = sql.NullFloat64{Float64: 0, Valid: true}
+		}
+
+		rowsToInsert = append(rowsToInsert, dbRow)
+	}
+
+	return rowsToInsert
+}
+
+// setProvenance fills in where each row's data came from. countries and
+// rates are nil for data that was not downloaded by this refresh, in which
+// case the stored row's provenance is kept.
+func setProvenance(rows, existing []model.CountryDBRow, countries *model.Provenance, rates *model.ExchangeRates) {
+	stored := make(map[string]*model.CountryDBRow, len(existing))
+	for i := range existing {
+		stored[existing[i].Name] = &existing[i]
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		old := stored[row.Name]
+
+		switch {
+		case countries != nil:
+			row.CountrySource = sql.NullString{String: countries.Source, Valid: countries.Source != ""}
+			row.CountrySourceURL = sql.NullString{String: countries.URL, Valid: countries.URL != ""}
+			row.CountriesFetchedAt = sql.NullTime{Time: countries.FetchedAt, Valid: !countries.FetchedAt.IsZero()}
+		case old != nil:
+			row.CountrySource = old.CountrySource
+			row.CountrySourceURL = old.CountrySourceURL
+			row.CountriesFetchedAt = old.CountriesFetchedAt
+		}
+
+		switch {
+		case rates != nil:
+			p, ok := rates.ProvenanceOf(row.CurrencyCode.String)
+			if ok && row.ExchangeRate.Valid {
+				row.RateSourceURL = sql.NullString{String: p.URL, Valid: p.URL != ""}
+				row.RatesUpdatedAt = sql.NullTime{Time: p.UpdatedAt, Valid: !p.UpdatedAt.IsZero()}
+				row.RatesFetchedAt = sql.NullTime{Time: p.FetchedAt, Valid: !p.FetchedAt.IsZero()}
+			}
+		case old != nil:
+			row.RateSourceURL = old.RateSourceURL
+			row.RatesUpdatedAt = old.RatesUpdatedAt
+			row.RatesFetchedAt = old.RatesFetchedAt
+		}
+	}
+}
+
+// dropDuplicateCodes clears ISO codes that an earlier row already uses, as
+// the stored codes are unique, and returns the names of the rows affected.
+func dropDuplicateCodes(rows []model.CountryDBRow) []string {
+	seen := map[string]bool{}
+	var affected []string
+	for i := range rows {
+		row := &rows[i]
+		dropped := false
+		for _, code := range []*sql.NullString{&row.Alpha2Code, &row.Alpha3Code, &row.NumericCode} {
+			if !code.Valid {
+				continue
+			}
+			// Alpha-2, alpha-3 and numeric codes never collide with each
+			// other, so one set covers all three.
+			if seen[code.String] {
+				*code = sql.NullString{}
+				dropped = true
+				continue
+			}
+			seen[code.String] = true
+		}
+		if dropped {
+			affected = append(affected, row.Name)
+		}
+	}
+	return affected
+}
+
+// upperAll returns codes upper-cased.
+func upperAll(codes []string) []string {
+	if codes == nil {
+		return nil
+	}
+	upper := make([]string, len(codes))
+	for i, code := range codes {
+		upper[i] = strings.ToUpper(code)
+	}
+	return upper
+}
+
+// buildCurrencyRows keeps every currency a country uses, in upstream order.
+// The first one with a code is treated as primary; duplicates are dropped.
+func buildCurrencyRows(currencies []model.CountryCurrency, exchangeData *model.ExchangeRates) []model.CountryCurrencyDBRow {
+	rows := make([]model.CountryCurrencyDBRow, 0, len(currencies))
+	seen := make(map[string]bool, len(currencies))
+
+	for _, currency := range currencies {
+		code := strings.ToUpper(strings.TrimSpace(currency.Code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		row := model.CountryCurrencyDBRow{
+			Code:      code,
+			Name:      sql.NullString{String: currency.Name, Valid: currency.Name != ""},
+			Symbol:    sql.NullString{String: currency.Symbol, Valid: currency.Symbol != ""},
+			IsPrimary: len(rows) == 0,
+		}
+		if rate, ok := exchangeData.Rates[code]; ok {
+			row.ExchangeRate = sql.NullFloat64{Float64: rate, Valid: true}
+			source := exchangeData.SourceOf(code)
+			row.RateSource = sql.NullString{String: source, Valid: source != ""}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// baseCurrency returns the configured base currency.
+func (s *RefreshService) baseCurrency() string {
+	if s.cfg.BaseCurrency == "" {
+		return DefaultBaseCurrency
+	}
+	return s.cfg.BaseCurrency
+}
+
+// storedRates returns the stored exchange rates quoted against base, which
+// differs from what is stored only after the base currency is reconfigured.
+func (s *RefreshService) storedRates(ctx context.Context, base string) (*model.ExchangeRates, error) {
+	stored, err := s.repo.GetStoredRates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored exchange rates: %w", err)
+	}
+	if len(stored.Rates) == 0 {
+		return stored, nil
+	}
+	rebased, err := rebaseRates(stored, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebase stored exchange rates: %w", err)
+	}
+	return rebased, nil
+}
+
+// rebaseRates re-expresses rates quoted against another currency in base
+// using cross rates. Rates without a base code are taken to be quoted
+// against base already.
+func rebaseRates(data *model.ExchangeRates, base string) (*model.ExchangeRates, error) {
+	if data.BaseCode == "" || strings.EqualFold(data.BaseCode, base) {
+		data.BaseCode = base
+		if _, ok := data.Rates[base]; !ok && data.Rates != nil {
+			data.Rates[base] = 1
+		}
+		return data, nil
+	}
+
+	pivot, ok := data.Rates[base]
+	if !ok || pivot <= 0 {
+		return nil, fmt.Errorf("rates quoted against %s have no rate for base currency %s", data.BaseCode, base)
+	}
+	rebased := *data
+	rebased.BaseCode = base
+	rebased.Rates = make(map[string]float64, len(data.Rates))
+	for code, rate := range data.Rates {
+		rebased.Rates[code] = rate / pivot
+	}
+	rebased.Rates[base] = 1
+	return &rebased, nil
+}
+
+// recordRateHistory appends the fetched rates to the history table and prunes
+// snapshots older than the configured retention. Countries are already
+// committed at this point, so failures are logged rather than returned.
+func (s *RefreshService) recordRateHistory(ctx context.Context, exchangeData *model.ExchangeRates, refreshTime time.Time) {
+	baseCode := exchangeData.BaseCode
+	if baseCode == "" {
+		baseCode = s.baseCurrency()
+	}
+	rateTimestamp := refreshTime
+	if exchangeData.TimeLastUpdateUnix > 0 {
+		rateTimestamp = time.Unix(exchangeData.TimeLastUpdateUnix, 0)
+	}
+
+	if err := s.repo.AppendRateHistory(ctx, baseCode, exchangeData, rateTimestamp); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to record exchange rate history")
+		return
+	}
+
+	if s.cfg.RateHistoryRetentionDays <= 0 {
+		return
+	}
+	cutoff := refreshTime.AddDate(0, 0, -s.cfg.RateHistoryRetentionDays)
+	pruned, err := s.repo.PruneRateHistory(ctx, cutoff)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to prune exchange rate history")
+		return
+	}
+	if pruned > 0 {
+		s.logger.Info().Int64("rows", pruned).Msg("Pruned exchange rate history")
+	}
+}
+
+func (s *RefreshService) generateAndLogSummary(ctx context.Context, refreshTime time.Time) {
+	total, err := s.repo.GetTotalCountries(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("ImageGen: Failed to get total countries")
+		return
+	}
+
+	top5, err := s.repo.GetTop5ByGDP(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("ImageGen: Failed to get top 5 countries by GDP")
+		return
+	}
+
+	err = s.imgGen.GenerateSummary(total, top5, refreshTime)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("ImageGen: Failed to generate summary image")
+		return
+	}
+
+	s.logger.Info().Msg("Summary image generated successfully")
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/justinndidit/forex/internal/errs"
+	"github.com/justinndidit/forex/internal/model"
+	"github.com/justinndidit/forex/internal/repository"
+	"github.com/rs/zerolog"
+)
+
+// SnapshotService writes and loads NDJSON snapshots of the stored dataset,
+// used to move a known-good dataset between environments.
+type SnapshotService struct {
+	logger *zerolog.Logger
+	repo   *repository.ForexRepository
+}
+
+func NewSnapshotService(logger *zerolog.Logger, repo *repository.ForexRepository) *SnapshotService {
+	return &SnapshotService{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// Export streams a snapshot to w: a header line, one line per stored row and
+// a trailer line with the row count.
+func (s *SnapshotService) Export(ctx context.Context, w io.Writer) error {
+	version, err := s.repo.SchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	buffered := bufio.NewWriter(w)
+	enc := json.NewEncoder(buffered)
+
+	header := model.SnapshotHeader{
+		FormatVersion: model.SnapshotFormatVersion,
+		SchemaVersion: version,
+		ExportedAt:    time.Now().UTC(),
+	}
+	if err := enc.Encode(model.SnapshotLine{Header: &header}); err != nil {
+		return err
+	}
+
+	var rows int64
+	err = s.repo.ExportSnapshot(ctx, func(table string, row map[string]any) error {
+		rows++
+		return enc.Encode(model.SnapshotLine{Table: table, Row: row})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export snapshot: %w", err)
+	}
+
+	if err := enc.Encode(model.SnapshotLine{Trailer: &model.SnapshotTrailer{Rows: rows}}); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// Restore replaces the stored dataset with the snapshot read from r. The
+// snapshot must have been exported at the database's current migration
+// level and must be complete; otherwise nothing is changed. It holds the
+// refresh lock throughout so no refresh writes over the restored data, and
+// fails with a *errs.RefreshInProgressError if a refresh is running.
+func (s *SnapshotService) Restore(ctx context.Context, r io.Reader) (*model.SnapshotRestoreResponse, error) {
+	lock, err := s.repo.TryLock(ctx, repository.RefreshLockName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take refresh lock: %w", err)
+	}
+	if lock == nil {
+		return nil, fmt.Errorf("cannot restore snapshot: %w", &errs.RefreshInProgressError{})
+	}
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), jobBookkeepingTimeout)
+		defer cancel()
+		lock.Release(releaseCtx)
+	}()
+
+	version, err := s.repo.SchemaVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var first model.SnapshotLine
+	if err := dec.Decode(&first); err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", errs.ErrSnapshotInvalid, err)
+	}
+	if first.Header == nil {
+		return nil, fmt.Errorf("%w: first line is not a header", errs.ErrSnapshotInvalid)
+	}
+	if first.Header.FormatVersion != model.SnapshotFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", errs.ErrSnapshotInvalid, first.Header.FormatVersion)
+	}
+	if first.Header.SchemaVersion != version {
+		return nil, fmt.Errorf("%w: snapshot schema version %d does not match database schema version %d",
+			errs.ErrSnapshotInvalid, first.Header.SchemaVersion, version)
+	}
+
+	var (
+		rows    int64
+		trailer *model.SnapshotTrailer
+	)
+	next := func() (string, map[string]any, error) {
+		if trailer != nil {
+			return "", nil, io.EOF
+		}
+
+		var line model.SnapshotLine
+		if err := dec.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", nil, fmt.Errorf("%w: snapshot ends without a trailer", errs.ErrSnapshotInvalid)
+			}
+			return "", nil, fmt.Errorf("%w: line %d: %v", errs.ErrSnapshotInvalid, rows+2, err)
+		}
+
+		switch {
+		case line.Trailer != nil:
+			if line.Trailer.Rows != rows {
+				return "", nil, fmt.Errorf("%w: trailer counts %d rows, snapshot has %d", errs.ErrSnapshotInvalid, line.Trailer.Rows, rows)
+			}
+			trailer = line.Trailer
+			return "", nil, io.EOF
+		case line.Table == "" || line.Row == nil:
+			return "", nil, fmt.Errorf("%w: line %d is not a table row", errs.ErrSnapshotInvalid, rows+2)
+		}
+		rows++
+		return line.Table, line.Row, nil
+	}
+
+	tables, err := s.repo.RestoreSnapshot(ctx, next)
+	if err != nil {
+		if errors.Is(err, errs.ErrSnapshotInvalid) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+
+	s.logger.Info().
+		Uint("schema_version", version).
+		Int64("rows", rows).
+		Time("exported_at", first.Header.ExportedAt).
+		Msg("Snapshot restored")
+
+	return &model.SnapshotRestoreResponse{
+		SchemaVersion: version,
+		Tables:        tables,
+		Rows:          rows,
+	}, nil
+}
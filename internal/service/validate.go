@@ -0,0 +1,225 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/justinndidit/forex/internal/currency"
+	"github.com/justinndidit/forex/internal/model"
+)
+
+// validationRules lists every rule in the order they are checked.
+var validationRules = []string{
+	model.RuleName,
+	model.RulePopulation,
+	model.RuleCurrencyCode,
+	model.RuleRate,
+}
+
+// enabledRules turns the configured rule list into a set. An empty list
+// enables every rule and "none" disables them all.
+func enabledRules(configured []string) map[string]bool {
+	rules := make(map[string]bool, len(validationRules))
+	if len(configured) == 0 {
+		for _, rule := range validationRules {
+			rules[rule] = true
+		}
+		return rules
+	}
+	for _, rule := range configured {
+		if rule == "none" {
+			return map[string]bool{}
+		}
+		rules[rule] = true
+	}
+	return rules
+}
+
+// rulesText renders a rule set in a canonical form, for storing which rules
+// the stored countries were validated with.
+func rulesText(rules map[string]bool) string {
+	names := make([]string, 0, len(rules))
+	for _, rule := range validationRules {
+		if rules[rule] {
+			names = append(names, rule)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// sanitizeRates drops rates that are not positive finite numbers, which
+// would otherwise be stored and divided by. It returns the rates to use and
+// the dropped ones; exchangeData itself is not modified.
+func sanitizeRates(exchangeData *model.ExchangeRates) (*model.ExchangeRates, map[string]float64) {
+	invalid := make(map[string]float64)
+	for code, rate := range exchangeData.Rates {
+		if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+			invalid[code] = rate
+		}
+	}
+	if len(invalid) == 0 {
+		return exchangeData, invalid
+	}
+
+	sanitized := *exchangeData
+	sanitized.Rates = make(map[string]float64, len(exchangeData.Rates)-len(invalid))
+	for code, rate := range exchangeData.Rates {
+		if _, ok := invalid[code]; !ok {
+			sanitized.Rates[code] = rate
+		}
+	}
+	return &sanitized, invalid
+}
+
+// validateCountries splits countries into those that pass every enabled rule
+// and quarantine records for the rest. invalidRates holds the rates dropped
+// by sanitizeRates, keyed by currency code.
+//
+// A currency that fails a rule is dropped from its country and recorded on
+// its own; the country is only quarantined when no currency is left, or when
+// it fails a rule of its own.
+func validateCountries(countries []model.Country, invalidRates map[string]float64, rules map[string]bool) ([]model.Country, []model.QuarantinedCountry) {
+	if len(rules) == 0 {
+		return countries, nil
+	}
+
+	valid := make([]model.Country, 0, len(countries))
+	var quarantined []model.QuarantinedCountry
+	for _, country := range countries {
+		kept, dropped := currencyFailures(country, invalidRates, rules)
+		failures := countryFailures(country, rules)
+		if len(dropped) > 0 && len(kept) == 0 {
+			failures = append(failures, dropped...)
+		}
+		if len(failures) > 0 {
+			quarantined = append(quarantined, quarantineRecord(country.Name, "", failures, country))
+			continue
+		}
+
+		for _, failure := range dropped {
+			quarantined = append(quarantined, quarantineRecord(country.Name, failure.currency.Code, []ruleFailure{failure}, failure.currency))
+		}
+		if len(dropped) > 0 {
+			country.Currencies = kept
+		}
+		valid = append(valid, country)
+	}
+	return valid, quarantined
+}
+
+func quarantineRecord(name, currencyCode string, failures []ruleFailure, record any) model.QuarantinedCountry {
+	reasons := make([]string, len(failures))
+	for i, failure := range failures {
+		reasons[i] = failure.reason
+	}
+	q := model.QuarantinedCountry{
+		CountryName: name,
+		Currency:    sql.NullString{String: currencyCode, Valid: currencyCode != ""},
+		Rule:        failures[0].rule,
+		Reason:      strings.Join(reasons, "; "),
+	}
+	if encoded, err := json.Marshal(record); err == nil {
+		q.Record = sql.NullString{String: string(encoded), Valid: true}
+	}
+	return q
+}
+
+type ruleFailure struct {
+	rule     string
+	reason   string
+	currency model.CountryCurrency
+}
+
+func countryFailures(country model.Country, rules map[string]bool) []ruleFailure {
+	var failures []ruleFailure
+	if rules[model.RuleName] && strings.TrimSpace(country.Name) == "" {
+		failures = append(failures, ruleFailure{rule: model.RuleName, reason: "name is empty"})
+	}
+	if rules[model.RulePopulation] && country.Population <= 0 {
+		failures = append(failures, ruleFailure{
+			rule:   model.RulePopulation,
+			reason: fmt.Sprintf("population %d is not positive", country.Population),
+		})
+	}
+	return failures
+}
+
+// currencyFailures checks each of a country's currencies. It returns the
+// currencies that pass, and a failure for each one that does not; a
+// currency failing both rules is reported once, for the first rule.
+// Currencies without a code are kept as they are.
+func currencyFailures(country model.Country, invalidRates map[string]float64, rules map[string]bool) ([]model.CountryCurrency, []ruleFailure) {
+	kept := make([]model.CountryCurrency, 0, len(country.Currencies))
+	var failures []ruleFailure
+	for _, c := range country.Currencies {
+		code := strings.ToUpper(strings.TrimSpace(c.Code))
+		if code == "" {
+			kept = append(kept, c)
+			continue
+		}
+		if rules[model.RuleCurrencyCode] && !currency.IsISO4217(code) {
+			failures = append(failures, ruleFailure{
+				rule:     model.RuleCurrencyCode,
+				reason:   fmt.Sprintf("currency code %q is not in ISO 4217", c.Code),
+				currency: c,
+			})
+			continue
+		}
+		if rate, ok := invalidRates[code]; ok && rules[model.RuleRate] {
+			failures = append(failures, ruleFailure{
+				rule:     model.RuleRate,
+				reason:   fmt.Sprintf("exchange rate %v for %s is not a positive number", rate, code),
+				currency: c,
+			})
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept, failures
+}
+
+// keepQuarantined carries the stored row of every quarantined country that
+// is already stored into rows, so a bad upstream record leaves the stored
+// country as it was instead of counting it as missing upstream. Records of
+// a single dropped currency are skipped; their country was stored.
+func keepQuarantined(rows, existing []model.CountryDBRow, quarantined []model.QuarantinedCountry) []model.CountryDBRow {
+	if len(quarantined) == 0 {
+		return rows
+	}
+
+	present := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		present[row.Name] = true
+	}
+	stored := make(map[string]*model.CountryDBRow, len(existing))
+	for i := range existing {
+		stored[existing[i].Name] = &existing[i]
+	}
+
+	for _, q := range quarantined {
+		if !q.WholeCountry() {
+			continue
+		}
+		name := strings.ToLower(q.CountryName)
+		if row, ok := stored[name]; ok && !present[name] {
+			rows = append(rows, *row)
+			present[name] = true
+		}
+	}
+	return rows
+}
+
+// countWholeCountries counts the quarantine records that kept a whole
+// country out.
+func countWholeCountries(quarantined []model.QuarantinedCountry) int {
+	count := 0
+	for i := range quarantined {
+		if quarantined[i].WholeCountry() {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/justinndidit/forex/internal/model"
+)
+
+func currencies(codes ...string) []model.CountryCurrency {
+	list := make([]model.CountryCurrency, len(codes))
+	for i, code := range codes {
+		list[i] = model.CountryCurrency{Code: code}
+	}
+	return list
+}
+
+func TestEnabledRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured []string
+		want       map[string]bool
+	}{
+		{
+			name:       "empty enables every rule",
+			configured: nil,
+			want: map[string]bool{
+				model.RuleName:         true,
+				model.RulePopulation:   true,
+				model.RuleCurrencyCode: true,
+				model.RuleRate:         true,
+			},
+		},
+		{
+			name:       "none disables every rule",
+			configured: []string{model.RuleName, "none"},
+			want:       map[string]bool{},
+		},
+		{
+			name:       "listed rules only",
+			configured: []string{model.RuleRate, model.RuleName},
+			want:       map[string]bool{model.RuleName: true, model.RuleRate: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enabledRules(tt.configured); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("enabledRules(%v) = %v, want %v", tt.configured, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeRates(t *testing.T) {
+	exchangeData := &model.ExchangeRates{Rates: map[string]float64{
+		"USD": 1,
+		"EUR": 0.9,
+		"ZER": 0,
+		"NEG": -2,
+		"NAN": math.NaN(),
+		"INF": math.Inf(1),
+	}}
+
+	sanitized, invalid := sanitizeRates(exchangeData)
+
+	wantRates := map[string]float64{"USD": 1, "EUR": 0.9}
+	if !reflect.DeepEqual(sanitized.Rates, wantRates) {
+		t.Errorf("sanitized rates = %v, want %v", sanitized.Rates, wantRates)
+	}
+	for _, code := range []string{"ZER", "NEG", "NAN", "INF"} {
+		if _, ok := invalid[code]; !ok {
+			t.Errorf("invalid rates missing %s", code)
+		}
+	}
+	if len(invalid) != 4 {
+		t.Errorf("got %d invalid rates, want 4", len(invalid))
+	}
+	if len(exchangeData.Rates) != 6 {
+		t.Errorf("sanitizeRates modified its input: %v", exchangeData.Rates)
+	}
+}
+
+func TestValidateCountries(t *testing.T) {
+	allRules := enabledRules(nil)
+
+	type quarantine struct {
+		country  string
+		currency string
+		rule     string
+	}
+
+	tests := []struct {
+		name           string
+		countries      []model.Country
+		invalidRates   map[string]float64
+		rules          map[string]bool
+		wantCurrencies map[string][]string
+		wantQuarantine []quarantine
+	}{
+		{
+			name: "valid country is kept whole",
+			countries: []model.Country{
+				{Name: "France", Population: 67000000, Currencies: currencies("EUR")},
+			},
+			rules:          allRules,
+			wantCurrencies: map[string][]string{"France": {"EUR"}},
+		},
+		{
+			name: "non-ISO currency is dropped and the country kept",
+			countries: []model.Country{
+				{Name: "Guernsey", Population: 63000, Currencies: currencies("GBP", "GGP")},
+			},
+			rules:          allRules,
+			wantCurrencies: map[string][]string{"Guernsey": {"GBP"}},
+			wantQuarantine: []quarantine{{"Guernsey", "GGP", model.RuleCurrencyCode}},
+		},
+		{
+			name: "dropped first currency leaves the next as primary",
+			countries: []model.Country{
+				{Name: "Tuvalu", Population: 11000, Currencies: currencies("TVD", "AUD")},
+			},
+			rules:          allRules,
+			wantCurrencies: map[string][]string{"Tuvalu": {"AUD"}},
+			wantQuarantine: []quarantine{{"Tuvalu", "TVD", model.RuleCurrencyCode}},
+		},
+		{
+			name: "country left without a currency is quarantined",
+			countries: []model.Country{
+				{Name: "Jersey", Population: 100000, Currencies: currencies("JEP")},
+			},
+			rules:          allRules,
+			wantQuarantine: []quarantine{{"Jersey", "", model.RuleCurrencyCode}},
+		},
+		{
+			name: "invalid rate drops the currency",
+			countries: []model.Country{
+				{Name: "Zimbabwe", Population: 15000000, Currencies: currencies("ZWL", "USD")},
+			},
+			invalidRates:   map[string]float64{"ZWL": 0},
+			rules:          allRules,
+			wantCurrencies: map[string][]string{"Zimbabwe": {"USD"}},
+			wantQuarantine: []quarantine{{"Zimbabwe", "ZWL", model.RuleRate}},
+		},
+		{
+			name: "country without currencies passes",
+			countries: []model.Country{
+				{Name: "Antarctica", Population: 1000},
+			},
+			rules:          allRules,
+			wantCurrencies: map[string][]string{"Antarctica": nil},
+		},
+		{
+			name: "country rule quarantines the whole country",
+			countries: []model.Country{
+				{Name: "Nowhere", Population: 0, Currencies: currencies("EUR", "XYZ")},
+			},
+			rules:          allRules,
+			wantQuarantine: []quarantine{{"Nowhere", "", model.RulePopulation}},
+		},
+		{
+			name: "disabled rule is not checked",
+			countries: []model.Country{
+				{Name: "Jersey", Population: 100000, Currencies: currencies("JEP")},
+			},
+			rules:          map[string]bool{model.RuleName: true},
+			wantCurrencies: map[string][]string{"Jersey": {"JEP"}},
+		},
+		{
+			name: "no rules keeps everything",
+			countries: []model.Country{
+				{Name: "", Population: -1, Currencies: currencies("JEP")},
+			},
+			rules:          map[string]bool{},
+			wantCurrencies: map[string][]string{"": {"JEP"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, quarantined := validateCountries(tt.countries, tt.invalidRates, tt.rules)
+
+			var gotCurrencies map[string][]string
+			for _, country := range valid {
+				var codes []string
+				for _, c := range country.Currencies {
+					codes = append(codes, c.Code)
+				}
+				if gotCurrencies == nil {
+					gotCurrencies = make(map[string][]string)
+				}
+				gotCurrencies[country.Name] = codes
+			}
+			if !reflect.DeepEqual(gotCurrencies, tt.wantCurrencies) {
+				t.Errorf("valid countries = %v, want %v", gotCurrencies, tt.wantCurrencies)
+			}
+
+			var gotQuarantine []quarantine
+			for _, q := range quarantined {
+				if !q.Record.Valid || q.Reason == "" {
+					t.Errorf("quarantine record for %s has no record or reason", q.CountryName)
+				}
+				gotQuarantine = append(gotQuarantine, quarantine{q.CountryName, q.Currency.String, q.Rule})
+			}
+			if !reflect.DeepEqual(gotQuarantine, tt.wantQuarantine) {
+				t.Errorf("quarantined = %v, want %v", gotQuarantine, tt.wantQuarantine)
+			}
+		})
+	}
+}
+
+func TestValidateCountriesDoesNotModifyInput(t *testing.T) {
+	countries := []model.Country{
+		{Name: "Guernsey", Population: 63000, Currencies: currencies("GBP", "GGP")},
+	}
+
+	validateCountries(countries, nil, enabledRules(nil))
+
+	if len(countries[0].Currencies) != 2 {
+		t.Errorf("input currencies = %v, want both kept", countries[0].Currencies)
+	}
+}
+
+func TestKeepQuarantined(t *testing.T) {
+	existing := []model.CountryDBRow{{Name: "jersey"}, {Name: "guernsey"}}
+	rows := []model.CountryDBRow{{Name: "guernsey"}}
+	_, quarantined := validateCountries([]model.Country{
+		{Name: "Jersey", Population: 100000, Currencies: currencies("JEP")},
+		{Name: "Guernsey", Population: 63000, Currencies: currencies("GBP", "GGP")},
+	}, nil, enabledRules(nil))
+
+	got := keepQuarantined(rows, existing, quarantined)
+
+	var names []string
+	for _, row := range got {
+		names = append(names, row.Name)
+	}
+	want := []string{"guernsey", "jersey"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("keepQuarantined rows = %v, want %v", names, want)
+	}
+	if n := countWholeCountries(quarantined); n != 1 {
+		t.Errorf("countWholeCountries = %d, want 1", n)
+	}
+}
@@ -0,0 +1,156 @@
+package upstream
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const DefaultCacheDir = "cache/upstream"
+
+// ErrNotModified is returned by Client.Get when the upstream answered a
+// conditional request with 304, meaning the data the caller stored from the
+// previous fetch is still current.
+var ErrNotModified = errors.New("upstream data not modified")
+
+type requireBodyKey struct{}
+
+// RequireBody marks ctx so that Client.Get answers a 304 with the cached
+// response body instead of ErrNotModified. Use it when the caller has no
+// stored copy of the parsed data to fall back on.
+func RequireBody(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requireBodyKey{}, true)
+}
+
+// RequiresBody reports whether ctx was marked with RequireBody.
+func RequiresBody(ctx context.Context) bool {
+	v, _ := ctx.Value(requireBodyKey{}).(bool)
+	return v
+}
+
+type pendingKey struct{}
+
+// Pending holds the responses fetched under a context returned by
+// DeferCache. They are cached only on Commit, once the caller has stored
+// the data they carry; otherwise the next conditional request would be
+// answered with 304 for data that was never stored.
+type Pending struct {
+	mu      sync.Mutex
+	entries []pendingEntry
+}
+
+type pendingEntry struct {
+	client *Client
+	entry  cacheEntry
+	body   []byte
+}
+
+// DeferCache marks ctx so that responses fetched with it are held in the
+// returned Pending instead of being cached straight away.
+func DeferCache(ctx context.Context) (context.Context, *Pending) {
+	p := &Pending{}
+	return context.WithValue(ctx, pendingKey{}, p), p
+}
+
+func pendingFrom(ctx context.Context) *Pending {
+	p, _ := ctx.Value(pendingKey{}).(*Pending)
+	return p
+}
+
+func (p *Pending) add(client *Client, entry cacheEntry, body []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries = append(p.entries, pendingEntry{client: client, entry: entry, body: body})
+}
+
+// Commit caches the held responses. Failures are logged; they only cost a
+// full download next time.
+func (p *Pending) Commit() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.entries {
+		e.client.store(e.entry, e.body)
+	}
+	p.entries = nil
+}
+
+// cacheEntry holds the validators of a cached response. The body is stored
+// next to it in its own file.
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+}
+
+// diskCache keeps the last successful response of each URL on disk.
+type diskCache struct {
+	dir  string
+	name string
+}
+
+func (c *diskCache) paths(url string) (meta, body string) {
+	sum := sha256.Sum256([]byte(url))
+	base := filepath.Join(c.dir, c.name+"-"+hex.EncodeToString(sum[:8]))
+	return base + ".json", base + ".body"
+}
+
+// load returns the validators for url if both they and the body are on disk.
+func (c *diskCache) load(url string) (*cacheEntry, bool) {
+	metaPath, bodyPath := c.paths(url)
+
+	raw, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil || entry.URL != url {
+		return nil, false
+	}
+	if entry.ETag == "" && entry.LastModified == "" {
+		return nil, false
+	}
+	if _, err := os.Stat(bodyPath); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *diskCache) body(url string) ([]byte, error) {
+	_, bodyPath := c.paths(url)
+	return os.ReadFile(bodyPath)
+}
+
+// store writes body first and the validators last, each via a rename, so a
+// crash never leaves validators pointing at a partial body.
+func (c *diskCache) store(entry cacheEntry, body []byte) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+
+	metaPath, bodyPath := c.paths(entry.URL)
+	if err := writeFileAtomic(bodyPath, body); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(metaPath, raw)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,277 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultTimeout      = 15 * time.Second
+	DefaultMaxRetries   = 3
+	DefaultBackoffBase  = 500 * time.Millisecond
+	DefaultBackoffMax   = 10 * time.Second
+	DefaultMaxBodyBytes = 20 << 20 // 20 MiB
+)
+
+var ErrBodyTooLarge = errors.New("response body exceeds size limit")
+
+// StatusError is returned when the upstream answers with a non-200 status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "bad status: " + e.Status
+}
+
+// Options tunes a Client. Zero values fall back to the package defaults;
+// a negative MaxRetries disables retries.
+type Options struct {
+	Timeout      time.Duration
+	MaxRetries   int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	MaxBodyBytes int64
+	// CacheDir enables the on-disk response cache and conditional requests.
+	// Empty disables both.
+	CacheDir string
+}
+
+// Client performs idempotent GETs against an upstream API with a per-attempt
+// timeout, bounded retries with exponential backoff and jitter, and a cap on
+// the response size. With a cache directory it also revalidates responses
+// using ETag and Last-Modified.
+type Client struct {
+	name       string
+	logger     *zerolog.Logger
+	httpClient *http.Client
+	opts       Options
+	cache      *diskCache
+}
+
+// response is the outcome of a single attempt.
+type response struct {
+	body         []byte
+	notModified  bool
+	etag         string
+	lastModified string
+}
+
+func NewClient(name string, opts Options, logger *zerolog.Logger) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.BackoffBase <= 0 {
+		opts.BackoffBase = DefaultBackoffBase
+	}
+	if opts.BackoffMax <= 0 {
+		opts.BackoffMax = DefaultBackoffMax
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	c := &Client{
+		name:       name,
+		logger:     logger,
+		httpClient: &http.Client{},
+		opts:       opts,
+	}
+	if opts.CacheDir != "" {
+		c.cache = &diskCache{dir: opts.CacheDir, name: name}
+	}
+	return c
+}
+
+// Get fetches url. When a cached copy exists the request is conditional;
+// a 304 yields ErrNotModified, or the cached body if ctx was marked with
+// RequireBody.
+func (c *Client) Get(ctx context.Context, url string) ([]byte, error) {
+	var cached *cacheEntry
+	if c.cache != nil {
+		cached, _ = c.cache.load(url)
+	}
+
+	var lastErr error
+
+	for attempt := 0; ; attempt++ {
+		resp, retryAfter, err := c.do(ctx, url, cached)
+		if err == nil {
+			return c.finish(ctx, url, resp)
+		}
+		lastErr = err
+
+		if attempt >= c.opts.MaxRetries || !retryable(ctx, err) {
+			break
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			break
+		}
+
+		c.logger.Warn().
+			Err(err).
+			Str("source", c.name).
+			Int("attempt", attempt+1).
+			Dur("retry_in", wait).
+			Msg("upstream request failed, retrying")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return nil, lastErr
+}
+
+// finish turns a successful attempt into Get's result and keeps the cache
+// up to date, or leaves that to the Pending in ctx, if any.
+func (c *Client) finish(ctx context.Context, url string, resp *response) ([]byte, error) {
+	if resp.notModified {
+		if !RequiresBody(ctx) {
+			c.logger.Info().Str("source", c.name).Msg("upstream data not modified")
+			return nil, ErrNotModified
+		}
+		body, err := c.cache.body(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cached body: %w", err)
+		}
+		return body, nil
+	}
+
+	if c.cache != nil && (resp.etag != "" || resp.lastModified != "") {
+		entry := cacheEntry{
+			URL:          url,
+			ETag:         resp.etag,
+			LastModified: resp.lastModified,
+			StoredAt:     time.Now(),
+		}
+		if pending := pendingFrom(ctx); pending != nil {
+			pending.add(c, entry, resp.body)
+		} else {
+			c.store(entry, resp.body)
+		}
+	}
+	return resp.body, nil
+}
+
+func (c *Client) store(entry cacheEntry, body []byte) {
+	if err := c.cache.store(entry, body); err != nil {
+		c.logger.Warn().Err(err).Str("source", c.name).Msg("failed to cache upstream response")
+	}
+}
+
+// do performs a single attempt, made conditional when cached is set.
+// retryAfter is set when the upstream sent a usable Retry-After header.
+func (c *Client) do(ctx context.Context, url string, cached *cacheEntry) (*response, time.Duration, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return &response{notModified: true}, 0, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// Drain a little so the connection can be reused.
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return nil, parseRetryAfter(resp.Header.Get("Retry-After")), &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.opts.MaxBodyBytes+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read body: %w", err)
+	}
+	if int64(len(body)) > c.opts.MaxBodyBytes {
+		return nil, 0, fmt.Errorf("%w (%d bytes)", ErrBodyTooLarge, c.opts.MaxBodyBytes)
+	}
+
+	return &response{
+		body:         body,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, 0, nil
+}
+
+// backoff returns the full-jitter exponential delay before retry attempt+1.
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.opts.BackoffBase << attempt
+	if ceiling <= 0 || ceiling > c.opts.BackoffMax {
+		ceiling = c.opts.BackoffMax
+	}
+	return time.Duration(rand.Int63n(int64(ceiling))) + time.Millisecond
+}
+
+func retryable(ctx context.Context, err error) bool {
+	// The caller gave up; retrying cannot help.
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	if errors.Is(err, ErrBodyTooLarge) {
+		return false
+	}
+
+	// Everything else is a transport error or a per-attempt timeout.
+	return true
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestDeferCacheOnlyCachesOnCommit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	client := NewClient("test", Options{CacheDir: t.TempDir(), MaxRetries: -1}, &logger)
+
+	// Not committed: the next request is unconditional.
+	ctx, _ := DeferCache(context.Background())
+	if _, err := client.Get(ctx, server.URL); err != nil {
+		t.Fatalf("first Get: %v", err)
+	}
+	ctx, pending := DeferCache(context.Background())
+	body, err := client.Get(ctx, server.URL)
+	if err != nil || string(body) != "body" {
+		t.Fatalf("Get after an uncommitted fetch = %q, %v; want the body", body, err)
+	}
+
+	// Committed: the next request is conditional.
+	pending.Commit()
+	if _, err := client.Get(context.Background(), server.URL); !errors.Is(err, ErrNotModified) {
+		t.Fatalf("Get after commit: err = %v, want ErrNotModified", err)
+	}
+	body, err = client.Get(RequireBody(context.Background()), server.URL)
+	if err != nil || string(body) != "body" {
+		t.Fatalf("Get with RequireBody = %q, %v; want the cached body", body, err)
+	}
+}
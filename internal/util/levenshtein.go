@@ -0,0 +1,29 @@
+package util
+
+// Levenshtein returns the edit distance between a and b: the number of
+// single-character insertions, deletions and substitutions that turn one
+// into the other. Characters are compared as runes.
+func Levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	if len(s) < len(t) {
+		s, t = t, s
+	}
+
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		curr[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(t)]
+}
@@ -0,0 +1,26 @@
+package util
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"niger", "niger", 0},
+		{"niger", "nigeria", 2},
+		{"nigeria", "niger", 2},
+		{"kitten", "sitting", 3},
+		{"untied", "united", 2},
+		{"côte", "cote", 1},
+	}
+
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
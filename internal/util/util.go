@@ -2,11 +2,7 @@ package util
 
 import (
 	"encoding/json"
-	"fmt"
-	"io"
-	"math/rand"
 	"net/http"
-	"sync"
 )
 
 func WriteJsonError(w http.ResponseWriter, status int, message string, details *string) {
@@ -20,43 +16,28 @@ func WriteJsonError(w http.ResponseWriter, status int, message string, details *
 	json.NewEncoder(w).Encode(ErrorResponse{Error: message, Details: details})
 }
 
-func WriteJsonSuccess(w http.ResponseWriter, status int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
-}
-
-type FetchResult struct {
-	URL  string
-	Body []byte
-	Err  error
-}
-
-func FetchData(url string, wg *sync.WaitGroup, resultChan chan<- FetchResult) {
-	defer wg.Done()
-
-	resp, err := http.Get(url)
-	if err != nil {
-		resultChan <- FetchResult{URL: url, Err: fmt.Errorf("failed to fetch: %v", err)}
-		return
+// WriteJsonErrorWith writes an error body like WriteJsonError, with the
+// fields of payload, a struct or map, next to "error" and "details".
+func WriteJsonErrorWith(w http.ResponseWriter, status int, message string, details *string, payload any) {
+	var body map[string]json.RawMessage
+	if encoded, err := json.Marshal(payload); err == nil {
+		json.Unmarshal(encoded, &body)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		resultChan <- FetchResult{URL: url, Err: fmt.Errorf("bad status: %s", resp.Status)}
-		return
+	if body == nil {
+		body = map[string]json.RawMessage{}
 	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		resultChan <- FetchResult{URL: url, Err: fmt.Errorf("failed to read body: %v", err)}
-		return
+	body["error"], _ = json.Marshal(message)
+	if details != nil {
+		body["details"], _ = json.Marshal(*details)
 	}
-	resultChan <- FetchResult{URL: url, Body: body}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
 }
 
-func RandFloatRange() float64 {
-	min := 1000.0
-	max := 2000.0
-	return min + rand.Float64()*(max-min)
+func WriteJsonSuccess(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
 }
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteJsonErrorWith(t *testing.T) {
+	details := "try again later"
+	runID := int64(9007199254740993)
+
+	tests := []struct {
+		name    string
+		details *string
+		payload any
+		want    string
+	}{
+		{
+			name: "no payload",
+			want: `{"error":"Conflict"}`,
+		},
+		{
+			name:    "payload fields next to error and details",
+			details: &details,
+			payload: struct {
+				RunID *int64 `json:"run_id,omitempty"`
+			}{RunID: &runID},
+			want: `{"details":"try again later","error":"Conflict","run_id":9007199254740993}`,
+		},
+		{
+			name:    "payload cannot replace the error",
+			payload: map[string]string{"error": "other"},
+			want:    `{"error":"Conflict"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			WriteJsonErrorWith(rec, http.StatusConflict, "Conflict", tt.details, tt.payload)
+
+			if rec.Code != http.StatusConflict {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}